@@ -4,15 +4,29 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
-	"time"
+	"net/url"
+	"strings"
 )
 
 const (
-	anthropicAPIBaseURL = "https://api.anthropic.com/v1"
-	anthropicAPIVersion = "2023-06-01"
+	anthropicAPIBaseURL       = "https://api.anthropic.com/v1"
+	anthropicAPIVersion       = "2023-06-01"
+	anthropicDefaultMaxTokens = 4096
 )
 
+var anthropicErrorStatuses = map[string]int{
+	"invalid_request_error": http.StatusBadRequest,
+	"authentication_error":  http.StatusUnauthorized,
+	"permission_error":      http.StatusForbidden,
+	"not_found_error":       http.StatusNotFound,
+	"request_too_large":     http.StatusRequestEntityTooLarge,
+	"rate_limit_error":      http.StatusTooManyRequests,
+	"api_error":             http.StatusInternalServerError,
+	"overloaded_error":      529,
+}
+
 type AnthropicProvider struct {
 	client  *http.Client
 	apiKey  string
@@ -21,76 +35,332 @@ type AnthropicProvider struct {
 }
 
 type anthropicRequest struct {
-	Model     string             `json:"model"`
-	Messages  []anthropicMessage `json:"messages"`
-	MaxTokens int                `json:"max_tokens"`
+	Model     string               `json:"model"`
+	System    []anthropicTextBlock `json:"system,omitempty"`
+	Messages  []anthropicMessage   `json:"messages"`
+	MaxTokens int                  `json:"max_tokens"`
+	Stream    bool                 `json:"stream,omitempty"`
+
+	Temperature   *float64 `json:"temperature,omitempty"`
+	TopP          *float64 `json:"top_p,omitempty"`
+	StopSequences []string `json:"stop_sequences,omitempty"`
+
+	Tools      []anthropicTool      `json:"tools,omitempty"`
+	ToolChoice *anthropicToolChoice `json:"tool_choice,omitempty"`
+
+	Thinking *anthropicThinking `json:"thinking,omitempty"`
+}
+
+type anthropicThinking struct {
+	Type         string `json:"type"`
+	BudgetTokens int    `json:"budget_tokens"`
+}
+
+type anthropicTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"input_schema"`
+}
+
+type anthropicToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
 }
 
 type anthropicMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role    string               `json:"role"`
+	Content []anthropicTextBlock `json:"content"`
+}
+
+type anthropicTextBlock struct {
+	Type         string                 `json:"type"`
+	Text         string                 `json:"text"`
+	CacheControl *anthropicCacheControl `json:"cache_control,omitempty"`
+}
+
+type anthropicCacheControl struct {
+	Type string `json:"type"`
+}
+
+type anthropicUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+func (u anthropicUsage) toUsage() Usage {
+	return Usage{
+		InputTokens:      u.InputTokens,
+		OutputTokens:     u.OutputTokens,
+		CacheReadTokens:  u.CacheReadInputTokens,
+		CacheWriteTokens: u.CacheCreationInputTokens,
+	}
+}
+
+func newAnthropicTextBlocks(text string, cached bool) []anthropicTextBlock {
+	block := anthropicTextBlock{Type: "text", Text: text}
+	if cached {
+		block.CacheControl = &anthropicCacheControl{Type: "ephemeral"}
+	}
+	return []anthropicTextBlock{block}
 }
 
 type anthropicResponse struct {
 	Content []struct {
-		Text string `json:"text"`
-		Type string `json:"type"`
+		Text     string          `json:"text"`
+		Thinking string          `json:"thinking"`
+		Type     string          `json:"type"`
+		Input    json.RawMessage `json:"input,omitempty"`
 	} `json:"content"`
+	Usage anthropicUsage `json:"usage"`
 	Error *struct {
 		Message string `json:"message"`
 		Type    string `json:"type"`
 	} `json:"error,omitempty"`
 }
 
-func NewAnthropicProvider(apiKey, modelName string) (*AnthropicProvider, error) {
+type anthropicStreamEvent struct {
+	Type    string `json:"type"`
+	Message struct {
+		Usage anthropicUsage `json:"usage"`
+	} `json:"message"`
+	Delta struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		Thinking    string `json:"thinking"`
+		PartialJSON string `json:"partial_json"`
+	} `json:"delta"`
+	Usage anthropicUsage `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+	} `json:"error,omitempty"`
+}
+
+func NewAnthropicProvider(apiKey, modelName string, client *http.Client) (*AnthropicProvider, error) {
 	if apiKey == "" {
-		return nil, fmt.Errorf("anthropic API key is required")
+		return nil, missingCredentials("Anthropic", "anthropic API key is required")
 	}
 
 	return &AnthropicProvider{
-		client: &http.Client{
-			Timeout: 90 * time.Second,
-		},
+		client:  httpClientOrDefault(client, defaultRequestTimeout),
 		apiKey:  apiKey,
 		model:   modelName,
 		baseURL: anthropicAPIBaseURL,
 	}, nil
 }
 
-func (p *AnthropicProvider) Generate(ctx context.Context, prompt string) (string, error) {
+func (p *AnthropicProvider) SetBaseURL(baseURL string) {
+	p.baseURL = strings.TrimSuffix(baseURL, "/")
+}
+
+func (p *AnthropicProvider) apiBaseURL() string {
+	return p.baseURL
+}
+
+func (p *AnthropicProvider) newRequest(req Request, stream bool) (anthropicRequest, http.Header) {
+	system, conversation := splitSystemMessages(req.Messages)
+
+	apiMessages := make([]anthropicMessage, 0, len(conversation))
+	for i, m := range conversation {
+		cached := len(conversation) > 1 && i == len(conversation)-1
+		apiMessages = append(apiMessages, anthropicMessage{Role: string(m.Role), Content: newAnthropicTextBlocks(m.Content, cached)})
+	}
+
+	maxTokens := req.Options.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = anthropicDefaultMaxTokens
+	}
+	budget := req.Options.ThinkingBudget
+	if budget > 0 && maxTokens <= budget {
+		maxTokens = budget + anthropicDefaultMaxTokens
+	}
+
 	payload := anthropicRequest{
-		Model: p.model,
-		Messages: []anthropicMessage{
-			{Role: "user", Content: prompt},
-		},
-		MaxTokens: 4096,
+		Model:         p.model,
+		Messages:      apiMessages,
+		MaxTokens:     maxTokens,
+		Stream:        stream,
+		Temperature:   req.Options.Temperature,
+		TopP:          req.Options.TopP,
+		StopSequences: req.Options.StopSequences,
+	}
+	if system != "" {
+		payload.System = newAnthropicTextBlocks(system, true)
+	}
+	if schema := req.Options.Schema; schema != nil {
+		payload.Tools = []anthropicTool{{
+			Name:        schema.Name,
+			Description: "Return the answer as structured data.",
+			InputSchema: schema.Schema,
+		}}
+		payload.ToolChoice = &anthropicToolChoice{Type: "tool", Name: schema.Name}
+	}
+	if budget > 0 {
+		payload.Thinking = &anthropicThinking{Type: "enabled", BudgetTokens: budget}
+		payload.Temperature = nil
+		payload.TopP = nil
+		if payload.ToolChoice != nil {
+			payload.ToolChoice = &anthropicToolChoice{Type: "auto"}
+		}
 	}
 
 	headers := http.Header{}
 	headers.Set("Content-Type", "application/json")
-	headers.Set("x-api-key", p.apiKey)
+	if p.apiKey != "" {
+		headers.Set("x-api-key", p.apiKey)
+	}
 	headers.Set("anthropic-version", anthropicAPIVersion)
 
+	return payload, headers
+}
+
+func (p *AnthropicProvider) errorFromResponse(statusCode int, respBody []byte) error {
+	var apiResp anthropicResponse
+	if err := json.Unmarshal(respBody, &apiResp); err == nil && apiResp.Error != nil {
+		return newAPIError("Anthropic", p.model, statusCode, respBody, fmt.Sprintf("anthropic api error (type: %s): %s", apiResp.Error.Type, apiResp.Error.Message))
+	}
+	return newAPIError("Anthropic", p.model, statusCode, respBody, fmt.Sprintf("received non-200 status from anthropic: %d", statusCode))
+}
+
+func (p *AnthropicProvider) Generate(ctx context.Context, req Request) (*Response, error) {
+	payload, headers := p.newRequest(req, false)
+
 	respBody, statusCode, err := doAPIRequest(ctx, p.client, "POST", p.baseURL+"/messages", headers, payload)
 	if err != nil {
-		return "", fmt.Errorf("failed to send request to anthropic: %w", err)
+		return nil, requestError("Anthropic", fmt.Errorf("failed to send request to anthropic: %w", err))
+	}
+
+	if statusCode != http.StatusOK {
+		return nil, p.errorFromResponse(statusCode, respBody)
 	}
 
 	var apiResp anthropicResponse
 	if err := json.Unmarshal(respBody, &apiResp); err != nil {
-		return "", fmt.Errorf("failed to parse anthropic json response: %w", err)
+		return nil, fmt.Errorf("failed to parse anthropic json response: %w", err)
 	}
 
-	if statusCode != http.StatusOK {
-		if apiResp.Error != nil {
-			return "", fmt.Errorf("anthropic api error (type: %s): %s", apiResp.Error.Type, apiResp.Error.Message)
+	var text, thinking strings.Builder
+	for _, block := range apiResp.Content {
+		switch block.Type {
+		case "text":
+			text.WriteString(block.Text)
+		case "tool_use":
+			text.Write(block.Input)
+		case "thinking":
+			thinking.WriteString(block.Thinking)
 		}
-		return "", fmt.Errorf("received non-200 status from anthropic: %d", statusCode)
 	}
 
-	if len(apiResp.Content) == 0 || apiResp.Content[0].Text == "" {
-		return "", fmt.Errorf("received an empty or invalid response from anthropic")
+	result := newReasoningResponse(text.String(), thinking.String(), apiResp.Usage.toUsage(), thinkTagsFor(p.model, false))
+	if result.Text == "" {
+		return nil, fmt.Errorf("received an empty or invalid response from anthropic")
 	}
+	return result, nil
+}
+
+func (p *AnthropicProvider) GenerateStream(ctx context.Context, req Request, onChunk StreamHandler) (*Response, error) {
+	payload, headers := p.newRequest(req, true)
+
+	resp, err := doStreamRequest(ctx, p.client, "POST", p.baseURL+"/messages", headers, payload)
+	if err != nil {
+		return nil, requestError("Anthropic", fmt.Errorf("failed to send request to anthropic: %w", err))
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
 
-	return apiResp.Content[0].Text, nil
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, p.errorFromResponse(resp.StatusCode, respBody)
+	}
+
+	stream := newReasoningStream(ctx, onChunk, thinkTagsFor(p.model, false))
+	var usage Usage
+	err = readSSE(resp.Body, func(_, data string) error {
+		var event anthropicStreamEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return fmt.Errorf("failed to parse anthropic stream event: %w", err)
+		}
+
+		switch event.Type {
+		case "error":
+			if event.Error != nil {
+				return newAPIError("Anthropic", p.model, anthropicErrorStatuses[event.Error.Type], nil,
+					fmt.Sprintf("anthropic api error (type: %s): %s", event.Error.Type, event.Error.Message))
+			}
+			return fmt.Errorf("anthropic stream reported an error")
+		case "message_start":
+			usage = event.Message.Usage.toUsage()
+		case "message_delta":
+			usage.OutputTokens = event.Usage.OutputTokens
+		case "content_block_delta":
+			switch event.Delta.Type {
+			case "thinking_delta":
+				stream.Reasoning(event.Delta.Thinking)
+			case "input_json_delta":
+				stream.Text(event.Delta.PartialJSON)
+			default:
+				stream.Text(event.Delta.Text)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, requestError("Anthropic", fmt.Errorf("anthropic stream failed: %w", err))
+	}
+
+	result := stream.Response(usage)
+	if result.Text == "" {
+		return nil, fmt.Errorf("received an empty or invalid response from anthropic")
+	}
+	return result, nil
+}
+
+type anthropicModelsResponse struct {
+	Data []struct {
+		ID          string `json:"id"`
+		DisplayName string `json:"display_name"`
+	} `json:"data"`
+	HasMore bool   `json:"has_more"`
+	LastID  string `json:"last_id"`
+}
+
+func (p *AnthropicProvider) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	headers := http.Header{}
+	if p.apiKey != "" {
+		headers.Set("x-api-key", p.apiKey)
+	}
+	headers.Set("anthropic-version", anthropicAPIVersion)
+
+	var models []ModelInfo
+	afterID := ""
+	for {
+		endpoint := p.baseURL + "/models?limit=1000"
+		if afterID != "" {
+			endpoint += "&after_id=" + url.QueryEscape(afterID)
+		}
+
+		respBody, statusCode, err := doAPIRequest(ctx, p.client, "GET", endpoint, headers, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list anthropic models: %w", err)
+		}
+		if statusCode != http.StatusOK {
+			return nil, p.errorFromResponse(statusCode, respBody)
+		}
+
+		var page anthropicModelsResponse
+		if err := json.Unmarshal(respBody, &page); err != nil {
+			return nil, fmt.Errorf("failed to parse anthropic model list: %w", err)
+		}
+		for _, m := range page.Data {
+			models = append(models, ModelInfo{ID: m.ID, Name: m.DisplayName})
+		}
+
+		if !page.HasMore || page.LastID == "" {
+			return models, nil
+		}
+		afterID = page.LastID
+	}
 }
@@ -4,21 +4,34 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
-	"time"
+	"strings"
 )
 
-const azureAPIVersion = "2024-02-01"
+const (
+	azureAPIVersion          = "2024-02-01"
+	azureReasoningAPIVersion = "2024-12-01-preview"
+)
 
 type AzureProvider struct {
-	client  *http.Client
-	apiKey  string
-	baseURL string
+	client     *http.Client
+	apiKey     string
+	deployment string
+	baseURL    string
 }
 
 type azureRequest struct {
-	Model    string         `json:"model"`
-	Messages []azureMessage `json:"messages"`
+	Model          string                `json:"model"`
+	Messages       []azureMessage        `json:"messages"`
+	Stream         bool                  `json:"stream,omitempty"`
+	MaxTokens      int                   `json:"max_tokens,omitempty"`
+	Temperature    *float64              `json:"temperature,omitempty"`
+	TopP           *float64              `json:"top_p,omitempty"`
+	Stop           []string              `json:"stop,omitempty"`
+	ResponseFormat *openAIResponseFormat `json:"response_format,omitempty"`
+
+	ReasoningEffort string `json:"reasoning_effort,omitempty"`
 }
 type azureMessage struct {
 	Role    string `json:"role"`
@@ -30,61 +43,128 @@ type azureResponse struct {
 			Content string `json:"content"`
 		} `json:"message"`
 	} `json:"choices"`
+	Usage *openAIUsage `json:"usage,omitempty"`
 	Error *struct {
 		Message string `json:"message"`
 		Type    string `json:"type"`
 	} `json:"error,omitempty"`
 }
 
-func NewAzureProvider(apiKey, resourceName, deploymentName string) (*AzureProvider, error) {
+func NewAzureProvider(apiKey, resourceName, deploymentName string, client *http.Client) (*AzureProvider, error) {
 	if apiKey == "" || resourceName == "" || deploymentName == "" {
 		return nil, fmt.Errorf("API Key, Azure Resource Name, and Deployment Name (in 'model' field) are all required for Azure OpenAI")
 	}
 
-	url := fmt.Sprintf("https://%s.openai.azure.com/openai/deployments/%s/chat/completions?api-version=%s",
-		resourceName, deploymentName, azureAPIVersion)
+	provider := &AzureProvider{
+		client:     httpClientOrDefault(client, defaultRequestTimeout),
+		apiKey:     apiKey,
+		deployment: deploymentName,
+	}
+	provider.SetBaseURL(fmt.Sprintf("https://%s.openai.azure.com", resourceName))
+	return provider, nil
+}
 
-	return &AzureProvider{
-		client: &http.Client{
-			Timeout: 90 * time.Second,
-		},
-		apiKey:  apiKey,
-		baseURL: url,
-	}, nil
+func (p *AzureProvider) SetBaseURL(baseURL string) {
+	p.baseURL = fmt.Sprintf("%s/openai/deployments/%s/chat/completions", strings.TrimSuffix(baseURL, "/"), p.deployment)
 }
 
-func (p *AzureProvider) Generate(ctx context.Context, prompt string) (string, error) {
+func (p *AzureProvider) apiBaseURL() string {
+	return p.baseURL
+}
+
+func (p *AzureProvider) newRequest(req Request, stream bool) (azureRequest, http.Header) {
+	apiMessages := make([]azureMessage, 0, len(req.Messages))
+	for _, m := range req.Messages {
+		apiMessages = append(apiMessages, azureMessage{Role: string(m.Role), Content: m.Content})
+	}
+
 	payload := azureRequest{
-		Model: "",
-		Messages: []azureMessage{
-			{Role: "user", Content: prompt},
-		},
+		Model:          "",
+		Messages:       apiMessages,
+		Stream:         stream,
+		MaxTokens:      req.Options.MaxTokens,
+		Temperature:    req.Options.Temperature,
+		TopP:           req.Options.TopP,
+		Stop:           req.Options.StopSequences,
+		ResponseFormat: newOpenAIResponseFormat(req.Options.Schema, jsonModeObject),
+
+		ReasoningEffort: req.Options.ReasoningEffort,
 	}
 
 	headers := http.Header{}
 	headers.Set("Content-Type", "application/json")
 	headers.Set("api-key", p.apiKey)
 
-	respBody, statusCode, err := doAPIRequest(ctx, p.client, "POST", p.baseURL, headers, payload)
+	return payload, headers
+}
+
+func (p *AzureProvider) requestURL(req Request) string {
+	apiVersion := azureAPIVersion
+	if req.Options.ReasoningEffort != "" {
+		apiVersion = azureReasoningAPIVersion
+	}
+	return p.baseURL + "?api-version=" + apiVersion
+}
+
+func (p *AzureProvider) errorFromResponse(statusCode int, respBody []byte) error {
+	var apiResp azureResponse
+	if err := json.Unmarshal(respBody, &apiResp); err == nil && apiResp.Error != nil {
+		return newAPIError("Azure OpenAI", p.deployment, statusCode, respBody, fmt.Sprintf("azure api error (type: %s): %s", apiResp.Error.Type, apiResp.Error.Message))
+	}
+	return newAPIError("Azure OpenAI", p.deployment, statusCode, respBody, fmt.Sprintf("received non-200 status from azure: %s", string(respBody)))
+}
+
+func (p *AzureProvider) Generate(ctx context.Context, req Request) (*Response, error) {
+	payload, headers := p.newRequest(req, false)
+
+	respBody, statusCode, err := doAPIRequest(ctx, p.client, "POST", p.requestURL(req), headers, payload)
 	if err != nil {
-		return "", fmt.Errorf("failed to send request to azure: %w", err)
+		return nil, requestError("Azure OpenAI", fmt.Errorf("failed to send request to azure: %w", err))
+	}
+
+	if statusCode != http.StatusOK {
+		return nil, p.errorFromResponse(statusCode, respBody)
 	}
 
 	var apiResp azureResponse
 	if err := json.Unmarshal(respBody, &apiResp); err != nil {
-		return "", fmt.Errorf("failed to parse azure json response: %w", err)
+		return nil, fmt.Errorf("failed to parse azure json response: %w", err)
 	}
 
-	if statusCode != http.StatusOK {
-		if apiResp.Error != nil {
-			return "", fmt.Errorf("azure api error (type: %s): %s", apiResp.Error.Type, apiResp.Error.Message)
-		}
-		return "", fmt.Errorf("received non-200 status from azure: %s", string(respBody))
+	if len(apiResp.Choices) == 0 {
+		return nil, fmt.Errorf("received an empty or invalid response from azure")
+	}
+
+	result := newReasoningResponse(apiResp.Choices[0].Message.Content, "", apiResp.Usage.toUsage(), thinkTagsFor(p.deployment, false))
+	if result.Text == "" {
+		return nil, fmt.Errorf("received an empty or invalid response from azure")
 	}
+	return result, nil
+}
 
-	if len(apiResp.Choices) == 0 || apiResp.Choices[0].Message.Content == "" {
-		return "", fmt.Errorf("received an empty or invalid response from azure")
+func (p *AzureProvider) GenerateStream(ctx context.Context, req Request, onChunk StreamHandler) (*Response, error) {
+	payload, headers := p.newRequest(req, true)
+
+	resp, err := doStreamRequest(ctx, p.client, "POST", p.requestURL(req), headers, payload)
+	if err != nil {
+		return nil, requestError("Azure OpenAI", fmt.Errorf("failed to send request to azure: %w", err))
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, p.errorFromResponse(resp.StatusCode, respBody)
+	}
+
+	result, err := readOpenAIStream(ctx, "Azure OpenAI", resp.Body, onChunk, thinkTagsFor(p.deployment, false))
+	if err != nil {
+		return nil, requestError("Azure OpenAI", fmt.Errorf("azure stream failed: %w", err))
+	}
+	if result.Text == "" {
+		return nil, fmt.Errorf("received an empty or invalid response from azure")
 	}
 
-	return apiResp.Choices[0].Message.Content, nil
+	return result, nil
 }
@@ -2,76 +2,402 @@ package ai
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/bedrock"
+	bedrocktypes "github.com/aws/aws-sdk-go-v2/service/bedrock/types"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/document"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
 )
 
 type BedrockProvider struct {
-	client *bedrockruntime.Client
-	model  string
+	client       *bedrockruntime.Client
+	awsConfig    aws.Config
+	baseEndpoint *string
+	model        string
 }
 
-type bedrockClaudeRequest struct {
-	Prompt            string   `json:"prompt"`
-	MaxTokensToSample int      `json:"max_tokens_to_sample"`
-	StopSequences     []string `json:"stop_sequences"`
-}
-
-type bedrockClaudeResponse struct {
-	Completion string `json:"completion"`
-}
+func NewBedrockProvider(accessKey, secretKey, region, profile, roleARN, modelID string, client *http.Client) (*BedrockProvider, error) {
+	if (accessKey == "") != (secretKey == "") {
+		return nil, fmt.Errorf("AWS Access Key and Secret Key must be set together for Bedrock (or leave both empty to use your AWS profile)")
+	}
 
-func NewBedrockProvider(accessKey, secretKey, region, modelID string) (*BedrockProvider, error) {
-	if accessKey == "" || secretKey == "" || region == "" {
-		return nil, fmt.Errorf("AWS Access Key, Secret Key, and Region are required for Bedrock")
+	opts := []func(*awsconfig.LoadOptions) error{
+		awsconfig.WithRetryer(func() aws.Retryer { return aws.NopRetryer{} }),
+	}
+	if client != nil {
+		opts = append(opts, awsconfig.WithHTTPClient(client))
+	}
+	if region != "" {
+		opts = append(opts, awsconfig.WithRegion(region))
+	}
+	if accessKey != "" {
+		opts = append(opts, awsconfig.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(accessKey, secretKey, "")))
+	} else if profile != "" {
+		opts = append(opts, awsconfig.WithSharedConfigProfile(profile))
 	}
 
-	cfg, err := awsconfig.LoadDefaultConfig(context.TODO(),
-		awsconfig.WithRegion(region),
-		awsconfig.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(accessKey, secretKey, "")),
-	)
+	cfg, err := awsconfig.LoadDefaultConfig(context.Background(), opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load aws config: %w", err)
 	}
+	if cfg.Region == "" {
+		return nil, fmt.Errorf("AWS Region is required for Bedrock (set aws_region or configure a region for your AWS profile)")
+	}
+
+	if roleARN != "" {
+		cfg.Credentials = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), roleARN,
+			func(o *stscreds.AssumeRoleOptions) {
+				o.RoleSessionName = "gct"
+			},
+		))
+	}
 
 	return &BedrockProvider{
-		client: bedrockruntime.NewFromConfig(cfg),
-		model:  modelID,
+		client:    bedrockruntime.NewFromConfig(cfg),
+		awsConfig: cfg,
+		model:     modelID,
 	}, nil
 }
 
-func (p *BedrockProvider) Generate(ctx context.Context, prompt string) (string, error) {
-	claudePrompt := fmt.Sprintf("\n\nHuman: %s\n\nAssistant:", prompt)
+func (p *BedrockProvider) SetBaseURL(baseURL string) {
+	p.baseEndpoint = aws.String(strings.TrimSuffix(baseURL, "/"))
+	p.client = bedrockruntime.NewFromConfig(p.awsConfig, func(o *bedrockruntime.Options) {
+		o.BaseEndpoint = p.baseEndpoint
+	})
+}
+
+func (p *BedrockProvider) apiBaseURL() string {
+	if p.baseEndpoint != nil {
+		return *p.baseEndpoint
+	}
+	return fmt.Sprintf("https://bedrock-runtime.%s.amazonaws.com", p.awsConfig.Region)
+}
+
+func withBedrockRetry(ctx context.Context, call func() error) error {
+	var lastErr error
+	retryErr := retryLoop(ctx, func() (bool, time.Duration, error) {
+		lastErr = call()
+		if lastErr == nil || !isRetryableBedrockError(ctx, lastErr) {
+			return true, 0, nil
+		}
+		return false, 0, lastErr
+	})
+	if retryErr != nil {
+		return fmt.Errorf("request aborted while waiting to retry: %w", retryErr)
+	}
+	return lastErr
+}
+
+func isRetryableBedrockError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var throttling *types.ThrottlingException
+	var unavailable *types.ServiceUnavailableException
+	var internal *types.InternalServerException
+	var notReady *types.ModelNotReadyException
+	var modelTimeout *types.ModelTimeoutException
+	if errors.As(err, &throttling) || errors.As(err, &unavailable) || errors.As(err, &internal) ||
+		errors.As(err, &notReady) || errors.As(err, &modelTimeout) {
+		return true
+	}
+
+	var statusErr interface{ HTTPStatusCode() int }
+	if errors.As(err, &statusErr) {
+		return isRetryableStatus(statusErr.HTTPStatusCode(), nil)
+	}
+
+	var apiErr smithy.APIError
+	return !errors.As(err, &apiErr) && isRetryableError(ctx, err)
+}
+
+func bedrockError(err error, model string) error {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return requestError("Amazon Bedrock", err)
+	}
+
+	statusCode := 0
+	var statusErr interface{ HTTPStatusCode() int }
+	if errors.As(err, &statusErr) {
+		statusCode = statusErr.HTTPStatusCode()
+	}
+
+	return newAPIError("Amazon Bedrock", model, statusCode, []byte(apiErr.ErrorCode()), fmt.Sprintf("bedrock api error (%s): %s", apiErr.ErrorCode(), apiErr.ErrorMessage()))
+}
+
+func bedrockUsage(usage *types.TokenUsage) Usage {
+	if usage == nil {
+		return Usage{}
+	}
+	return Usage{
+		InputTokens:  int(aws.ToInt32(usage.InputTokens)),
+		OutputTokens: int(aws.ToInt32(usage.OutputTokens)),
+	}
+}
+
+func (p *BedrockProvider) resolveCredentials(ctx context.Context) error {
+	if p.awsConfig.Credentials == nil {
+		return missingCredentials("Amazon Bedrock", "no AWS credentials found (set aws_profile, run 'aws sso login', or provide access keys)")
+	}
+	_, err := p.awsConfig.Credentials.Retrieve(ctx)
+	if err == nil {
+		return nil
+	}
+	if ctx.Err() != nil {
+		return requestError("Amazon Bedrock", err)
+	}
+	return &APIError{
+		Provider: "Amazon Bedrock",
+		Kind:     ErrAuth,
+		Message:  fmt.Sprintf("failed to resolve AWS credentials (set aws_profile, run 'aws sso login', or provide access keys): %v", err),
+		Err:      err,
+	}
+}
+
+func (p *BedrockProvider) newConversation(req Request) ([]types.SystemContentBlock, []types.Message) {
+	systemPrompt, conversation := splitSystemMessages(req.Messages)
+
+	var system []types.SystemContentBlock
+	if systemPrompt != "" {
+		system = append(system, &types.SystemContentBlockMemberText{Value: systemPrompt})
+	}
+
+	apiMessages := make([]types.Message, 0, len(conversation))
+	for _, m := range conversation {
+		role := types.ConversationRoleUser
+		if m.Role == RoleAssistant {
+			role = types.ConversationRoleAssistant
+		}
 
-	payload := bedrockClaudeRequest{
-		Prompt:            claudePrompt,
-		MaxTokensToSample: 8192,
-		StopSequences:     []string{"\n\nHuman:"},
+		block := &types.ContentBlockMemberText{Value: m.Content}
+		if n := len(apiMessages); n > 0 && apiMessages[n-1].Role == role {
+			apiMessages[n-1].Content = append(apiMessages[n-1].Content, block)
+			continue
+		}
+		apiMessages = append(apiMessages, types.Message{
+			Role:    role,
+			Content: []types.ContentBlock{block},
+		})
 	}
 
-	body, err := json.Marshal(payload)
+	return system, apiMessages
+}
+
+func (p *BedrockProvider) newInferenceConfig(req Request) *types.InferenceConfiguration {
+	opts := req.Options
+	if opts.ThinkingBudget > 0 {
+		opts.Temperature = nil
+		opts.TopP = nil
+		if opts.MaxTokens <= opts.ThinkingBudget {
+			opts.MaxTokens = opts.ThinkingBudget + anthropicDefaultMaxTokens
+		}
+	}
+	if opts.MaxTokens <= 0 && opts.Temperature == nil && opts.TopP == nil && len(opts.StopSequences) == 0 {
+		return nil
+	}
+
+	inference := &types.InferenceConfiguration{StopSequences: opts.StopSequences}
+	if opts.MaxTokens > 0 {
+		inference.MaxTokens = aws.Int32(int32(opts.MaxTokens))
+	}
+	if opts.Temperature != nil {
+		inference.Temperature = aws.Float32(float32(*opts.Temperature))
+	}
+	if opts.TopP != nil {
+		inference.TopP = aws.Float32(float32(*opts.TopP))
+	}
+	return inference
+}
+
+func (p *BedrockProvider) newModelRequestFields(req Request) document.Interface {
+	if req.Options.ThinkingBudget <= 0 {
+		return nil
+	}
+	return document.NewLazyDocument(map[string]interface{}{
+		"thinking": map[string]interface{}{
+			"type":          "enabled",
+			"budget_tokens": req.Options.ThinkingBudget,
+		},
+	})
+}
+
+func (p *BedrockProvider) Generate(ctx context.Context, req Request) (*Response, error) {
+	if err := p.resolveCredentials(ctx); err != nil {
+		return nil, err
+	}
+	system, apiMessages := p.newConversation(req)
+
+	var output *bedrockruntime.ConverseOutput
+	err := withBedrockRetry(ctx, func() error {
+		var err error
+		output, err = p.client.Converse(ctx, &bedrockruntime.ConverseInput{
+			ModelId:         aws.String(p.model),
+			System:          system,
+			Messages:        apiMessages,
+			InferenceConfig: p.newInferenceConfig(req),
+
+			AdditionalModelRequestFields: p.newModelRequestFields(req),
+		})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to invoke bedrock model: %w", bedrockError(err, p.model))
+	}
+
+	message, ok := output.Output.(*types.ConverseOutputMemberMessage)
+	if !ok {
+		return nil, fmt.Errorf("received an empty or invalid response from bedrock")
+	}
+
+	var text, reasoning strings.Builder
+	for _, block := range message.Value.Content {
+		switch b := block.(type) {
+		case *types.ContentBlockMemberText:
+			text.WriteString(b.Value)
+		case *types.ContentBlockMemberReasoningContent:
+			if r, ok := b.Value.(*types.ReasoningContentBlockMemberReasoningText); ok {
+				reasoning.WriteString(aws.ToString(r.Value.Text))
+			}
+		}
+	}
+
+	result := newReasoningResponse(text.String(), reasoning.String(), bedrockUsage(output.Usage), thinkTagsFor(p.model, false))
+	if result.Text == "" {
+		return nil, fmt.Errorf("received an empty or invalid response from bedrock (stop reason: %s)", output.StopReason)
+	}
+	return result, nil
+}
+
+func (p *BedrockProvider) GenerateStream(ctx context.Context, req Request, onChunk StreamHandler) (*Response, error) {
+	if err := p.resolveCredentials(ctx); err != nil {
+		return nil, err
+	}
+	system, apiMessages := p.newConversation(req)
+
+	var output *bedrockruntime.ConverseStreamOutput
+	err := withBedrockRetry(ctx, func() error {
+		var err error
+		output, err = p.client.ConverseStream(ctx, &bedrockruntime.ConverseStreamInput{
+			ModelId:         aws.String(p.model),
+			System:          system,
+			Messages:        apiMessages,
+			InferenceConfig: p.newInferenceConfig(req),
+
+			AdditionalModelRequestFields: p.newModelRequestFields(req),
+		})
+		return err
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal bedrock request: %w", err)
+		return nil, fmt.Errorf("failed to invoke bedrock model: %w", bedrockError(err, p.model))
 	}
 
-	output, err := p.client.InvokeModel(ctx, &bedrockruntime.InvokeModelInput{
-		ModelId:     aws.String(p.model),
-		ContentType: aws.String("application/json"),
-		Body:        body,
+	stream := output.GetStream()
+	defer func() {
+		_ = stream.Close()
+	}()
+
+	reasoningStream := newReasoningStream(ctx, onChunk, thinkTagsFor(p.model, false))
+	var usage Usage
+	var stopReason types.StopReason
+	for event := range stream.Events() {
+		switch e := event.(type) {
+		case *types.ConverseStreamOutputMemberContentBlockDelta:
+			switch delta := e.Value.Delta.(type) {
+			case *types.ContentBlockDeltaMemberText:
+				reasoningStream.Text(delta.Value)
+			case *types.ContentBlockDeltaMemberReasoningContent:
+				if r, ok := delta.Value.(*types.ReasoningContentBlockDeltaMemberText); ok {
+					reasoningStream.Reasoning(r.Value)
+				}
+			}
+		case *types.ConverseStreamOutputMemberMessageStop:
+			stopReason = e.Value.StopReason
+		case *types.ConverseStreamOutputMemberMetadata:
+			usage = bedrockUsage(e.Value.Usage)
+		}
+	}
+	err = stream.Err()
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("bedrock stream failed: %w", bedrockError(err, p.model))
+	}
+
+	result := reasoningStream.Response(usage)
+	if result.Text == "" {
+		return nil, fmt.Errorf("received an empty or invalid response from bedrock (stop reason: %s)", stopReason)
+	}
+	return result, nil
+}
+
+func (p *BedrockProvider) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	if err := p.resolveCredentials(ctx); err != nil {
+		return nil, err
+	}
+	client := bedrock.NewFromConfig(p.awsConfig, func(o *bedrock.Options) {
+		o.BaseEndpoint = p.baseEndpoint
+	})
+
+	var foundation *bedrock.ListFoundationModelsOutput
+	err := withBedrockRetry(ctx, func() error {
+		var callErr error
+		foundation, callErr = client.ListFoundationModels(ctx, &bedrock.ListFoundationModelsInput{
+			ByOutputModality: bedrocktypes.ModelModalityText,
+		})
+		return callErr
 	})
 	if err != nil {
-		return "", fmt.Errorf("failed to invoke bedrock model: %w", err)
+		return nil, bedrockError(err, "")
+	}
+
+	var models []ModelInfo
+	for _, m := range foundation.ModelSummaries {
+		if !slices.Contains(m.InferenceTypesSupported, bedrocktypes.InferenceTypeOnDemand) {
+			continue
+		}
+		models = append(models, ModelInfo{
+			ID:   aws.ToString(m.ModelId),
+			Name: strings.TrimSpace(aws.ToString(m.ProviderName) + " " + aws.ToString(m.ModelName)),
+		})
 	}
 
-	var resp bedrockClaudeResponse
-	if err := json.Unmarshal(output.Body, &resp); err != nil {
-		return "", fmt.Errorf("failed to unmarshal bedrock response: %w", err)
+	paginator := bedrock.NewListInferenceProfilesPaginator(client, &bedrock.ListInferenceProfilesInput{})
+	for paginator.HasMorePages() {
+		var page *bedrock.ListInferenceProfilesOutput
+		err := withBedrockRetry(ctx, func() error {
+			var callErr error
+			page, callErr = paginator.NextPage(ctx)
+			return callErr
+		})
+		if err != nil {
+			return nil, bedrockError(err, "")
+		}
+		for _, profile := range page.InferenceProfileSummaries {
+			if profile.Status != bedrocktypes.InferenceProfileStatusActive {
+				continue
+			}
+			models = append(models, ModelInfo{
+				ID:   aws.ToString(profile.InferenceProfileId),
+				Name: aws.ToString(profile.InferenceProfileName),
+			})
+		}
 	}
 
-	return resp.Completion, nil
+	return models, nil
 }
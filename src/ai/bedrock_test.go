@@ -0,0 +1,35 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestBedrockResolvesCredentialsOnFirstRequest(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("AWS_CONFIG_FILE", home+"/config")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", home+"/credentials")
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	for _, name := range []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN", "AWS_PROFILE",
+		"AWS_WEB_IDENTITY_TOKEN_FILE", "AWS_CONTAINER_CREDENTIALS_FULL_URI", "AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"} {
+		t.Setenv(name, "")
+	}
+
+	provider, err := NewBedrockProvider("", "", "us-east-1", "", "", "anthropic.claude-3-haiku-20240307-v1:0", nil)
+	if err != nil {
+		t.Fatalf("missing credentials should not fail at construction: %v", err)
+	}
+
+	_, err = provider.Generate(context.Background(), Request{Messages: UserPrompt("hi")})
+	if !errors.Is(err, ErrAuth) {
+		t.Fatalf("expected an authentication error, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := provider.Generate(ctx, Request{Messages: UserPrompt("hi")}); errors.Is(err, ErrAuth) || !errors.Is(err, ErrCancelled) {
+		t.Fatalf("a cancelled request should report the cancellation, got %v", err)
+	}
+}
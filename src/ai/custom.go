@@ -0,0 +1,169 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gct/src/config"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	dialectOpenAIChat        = "openai-chat"
+	dialectAnthropicMessages = "anthropic-messages"
+	dialectGemini            = "gemini"
+)
+
+const (
+	authBearer = "bearer"
+	authHeader = "header"
+	authQuery  = "query"
+	authNone   = "none"
+)
+
+type customProviderTransport struct {
+	base     http.RoundTripper
+	auth     string
+	authName string
+	apiKey   string
+	body     map[string]interface{}
+}
+
+func (t *customProviderTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+
+	switch t.auth {
+	case authBearer:
+		req.Header.Set("Authorization", "Bearer "+t.apiKey)
+	case authHeader:
+		req.Header.Set(t.authName, t.apiKey)
+	case authQuery:
+		query := req.URL.Query()
+		query.Set(t.authName, t.apiKey)
+		req.URL.RawQuery = query.Encode()
+	}
+
+	if len(t.body) > 0 && req.Body != nil && strings.HasPrefix(req.Header.Get("Content-Type"), "application/json") {
+		data, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+
+		var payload map[string]interface{}
+		if err := json.Unmarshal(data, &payload); err != nil {
+			return nil, fmt.Errorf("failed to add custom body fields: %w", err)
+		}
+		for key, value := range t.body {
+			payload[key] = value
+		}
+		data, err = json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add custom body fields: %w", err)
+		}
+
+		req.Body = io.NopCloser(bytes.NewReader(data))
+		req.ContentLength = int64(len(data))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(data)), nil
+		}
+	}
+
+	return t.base.RoundTrip(req)
+}
+
+func findCustomProvider(providers []config.CustomProviderConfig, providerName string) (config.CustomProviderConfig, bool) {
+	for _, custom := range providers {
+		if strings.ToLower(strings.ReplaceAll(custom.Name, " ", "")) == providerName {
+			return custom, true
+		}
+	}
+	return config.CustomProviderConfig{}, false
+}
+
+func NewCustomProvider(custom config.CustomProviderConfig, apiKey, modelName string, client *http.Client) (AIProvider, error) {
+	if custom.BaseURL == "" {
+		return nil, fmt.Errorf("custom provider '%s' requires a 'base_url'", custom.Name)
+	}
+
+	dialect := custom.Dialect
+	if dialect == "" {
+		dialect = dialectOpenAIChat
+	}
+
+	var auth, authName string
+	switch dialect {
+	case dialectOpenAIChat:
+		auth = authBearer
+	case dialectAnthropicMessages:
+		auth, authName = authHeader, "x-api-key"
+	case dialectGemini:
+		auth, authName = authQuery, "key"
+	default:
+		return nil, fmt.Errorf("invalid dialect '%s' for custom provider '%s': use openai-chat, anthropic-messages or gemini", custom.Dialect, custom.Name)
+	}
+	if custom.Auth != "" && custom.Auth != auth {
+		auth, authName = custom.Auth, ""
+	}
+	if custom.AuthName != "" {
+		authName = custom.AuthName
+	}
+
+	switch auth {
+	case authBearer, authNone:
+	case authHeader:
+		if authName == "" {
+			authName = "X-Api-Key"
+		}
+	case authQuery:
+		if authName == "" {
+			authName = "key"
+		}
+	default:
+		return nil, fmt.Errorf("invalid auth '%s' for custom provider '%s': use bearer, header, query or none", custom.Auth, custom.Name)
+	}
+	if auth != authNone && apiKey == "" {
+		return nil, missingCredentials(custom.Name, fmt.Sprintf("custom provider '%s' requires an API key; set 'api', or 'auth: none' if it needs none", custom.Name))
+	}
+
+	client = httpClientOrDefault(client, defaultRequestTimeout)
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	baseURL := strings.TrimSuffix(custom.BaseURL, "/")
+	if len(custom.Headers) > 0 {
+		base = newHeaderTransport(base, custom.Headers, baseURL)
+	}
+	customClient := *client
+	customClient.Transport = &customProviderTransport{
+		base:     base,
+		auth:     auth,
+		authName: authName,
+		apiKey:   apiKey,
+		body:     custom.Body,
+	}
+
+	switch dialect {
+	case dialectOpenAIChat:
+		return &OpenAICompatibleProvider{
+			client:  &customClient,
+			name:    custom.Name,
+			model:   modelName,
+			baseURL: baseURL,
+		}, nil
+	case dialectAnthropicMessages:
+		return &AnthropicProvider{
+			client:  &customClient,
+			model:   modelName,
+			baseURL: baseURL,
+		}, nil
+	}
+	return &GoogleProvider{
+		client:  &customClient,
+		model:   modelName,
+		baseURL: baseURL,
+	}, nil
+}
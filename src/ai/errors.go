@@ -0,0 +1,200 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+var (
+	ErrAuth           = errors.New("authentication failed")
+	ErrRateLimited    = errors.New("rate limited")
+	ErrQuotaExhausted = errors.New("quota exhausted")
+	ErrContextTooLong = errors.New("input is too long for the model")
+	ErrContentBlocked = errors.New("content blocked")
+	ErrModelNotFound  = errors.New("model not found")
+	ErrTimeout        = errors.New("request timed out")
+	ErrCancelled      = errors.New("operation cancelled")
+)
+
+const ModelNotFoundHint = "Run 'gct models' to see the available model names, then check that 'model' in gct.yaml is spelled exactly."
+
+var contextTooLongMarkers = []string{
+	"context_length_exceeded", "context length", "context window", "context size",
+	"prompt is too long", "input is too long", "too many tokens", "input token count",
+	"maximum number of tokens", "reduce the length",
+}
+
+var quotaMarkers = []string{
+	"insufficient_quota", "exceeded your current quota", "credit balance is too low",
+	"billing_hard_limit_reached", "billing to be enabled", "billing account", "servicequotaexceeded",
+}
+
+var blockedMarkers = []string{"content_filter", "content management policy", "responsibleaipolicyviolation"}
+
+var modelNotFoundMarkers = []string{"model_not_found", "does not exist", "not found", "invalid model", "model identifier is invalid"}
+
+type APIError struct {
+	Provider   string
+	StatusCode int
+	Kind       error
+	Message    string
+	Err        error
+}
+
+func newAPIError(provider, model string, statusCode int, detail []byte, message string) *APIError {
+	return &APIError{
+		Provider:   provider,
+		StatusCode: statusCode,
+		Kind:       classifyStatus(statusCode, string(detail)+" "+message, model),
+		Message:    message,
+	}
+}
+
+func requestError(provider string, err error) error {
+	var kind error
+	switch {
+	case errors.Is(err, context.Canceled):
+		kind = ErrCancelled
+	case errors.Is(err, context.DeadlineExceeded):
+		kind = ErrTimeout
+	default:
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			kind = ErrTimeout
+		}
+	}
+	if kind == nil {
+		return err
+	}
+	return &APIError{Provider: provider, Kind: kind, Message: err.Error(), Err: err}
+}
+
+func missingCredentials(provider, message string) error {
+	return &APIError{Provider: provider, Kind: ErrAuth, Message: message}
+}
+
+func classifyStatus(statusCode int, detail, model string) error {
+	detail = strings.ToLower(detail)
+	switch {
+	case statusCode != http.StatusTooManyRequests && containsAny(detail, contextTooLongMarkers),
+		statusCode == http.StatusRequestEntityTooLarge:
+		return ErrContextTooLong
+	case containsAny(detail, blockedMarkers):
+		return ErrContentBlocked
+	case statusCode == http.StatusPaymentRequired || containsAny(detail, quotaMarkers):
+		return ErrQuotaExhausted
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return ErrAuth
+	case statusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case statusCode == http.StatusRequestTimeout || statusCode == http.StatusGatewayTimeout:
+		return ErrTimeout
+	case statusCode == http.StatusNotFound && (strings.Contains(detail, "model_not_found") || model != "" && strings.Contains(detail, strings.ToLower(model))),
+		statusCode == http.StatusBadRequest && strings.Contains(detail, "model") && containsAny(detail, modelNotFoundMarkers):
+		return ErrModelNotFound
+	}
+	return nil
+}
+
+func containsAny(s string, markers []string) bool {
+	for _, marker := range markers {
+		if strings.Contains(s, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+func (e *APIError) Is(target error) bool {
+	return e.Kind != nil && target == e.Kind
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+func ShouldFallback(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, ErrCancelled) {
+		return false
+	}
+	for _, kind := range []error{ErrTimeout, ErrAuth, ErrRateLimited, ErrQuotaExhausted} {
+		if errors.Is(err, kind) {
+			return true
+		}
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode != 0 {
+		return isFallbackStatus(apiErr.StatusCode)
+	}
+
+	var statusErr interface{ HTTPStatusCode() int }
+	if errors.As(err, &statusErr) {
+		return isFallbackStatus(statusErr.HTTPStatusCode())
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr)
+}
+
+func isFallbackStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusUnauthorized, http.StatusPaymentRequired, http.StatusForbidden,
+		http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	}
+	return statusCode >= http.StatusInternalServerError
+}
+
+type BlockedError struct {
+	Provider   string
+	StatusCode int
+	Reason     string
+	Categories []string
+	Detail     string
+	Prompt     bool
+}
+
+func (e *BlockedError) Error() string {
+	subject := "response"
+	if e.Prompt {
+		subject = "prompt"
+	}
+	message := fmt.Sprintf("%s blocked the %s (%s)", e.Provider, subject, e.Reason)
+	if len(e.Categories) > 0 {
+		message += ": " + strings.Join(e.Categories, ", ")
+	}
+	if e.Detail != "" {
+		message += ". " + e.Detail
+	}
+	return message
+}
+
+func (e *BlockedError) Is(target error) bool {
+	return target == ErrContentBlocked
+}
+
+type TruncatedError struct {
+	Provider string
+	Reason   string
+	Response *Response
+}
+
+func (e *TruncatedError) Error() string {
+	return fmt.Sprintf("%s cut the response off at the output token limit (%s)", e.Provider, e.Reason)
+}
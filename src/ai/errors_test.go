@@ -0,0 +1,36 @@
+package ai
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestShouldFallbackSkipsBlockedContent(t *testing.T) {
+	err := fmt.Errorf("AI generation failed: %w", &BlockedError{Provider: "Google AI Studio", Prompt: true, Reason: "SAFETY"})
+	if ShouldFallback(err) {
+		t.Fatal("a blocked prompt should not be sent to the next provider")
+	}
+}
+
+func TestClassifyStatus(t *testing.T) {
+	tests := []struct {
+		status int
+		detail string
+		model  string
+		want   error
+	}{
+		{404, `{"error":{"code":"model_not_found","message":"The model 'gpt-9' does not exist"}}`, "gpt-9", ErrModelNotFound},
+		{404, `{"error":"model 'Llama3.2' not found"}`, "llama3.2", ErrModelNotFound},
+		{404, `404 page not found`, "gpt-4o", nil},
+		{404, `No route for /v1/models/chat`, "gpt-4o", nil},
+		{400, `{"error":{"message":"invalid model ID"}}`, "gpt-4o", ErrModelNotFound},
+		{429, `You exceeded your current quota, please check your plan and billing details.`, "gpt-4o", ErrQuotaExhausted},
+		{403, `The caller does not have permission to view billing reports`, "gpt-4o", ErrAuth},
+		{400, `{"error":{"message":"unknown field 'billing_id'"}}`, "gpt-4o", nil},
+	}
+	for _, tt := range tests {
+		if got := classifyStatus(tt.status, tt.detail, tt.model); got != tt.want {
+			t.Errorf("classifyStatus(%d, %q) = %v, want %v", tt.status, tt.detail, got, tt.want)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package ai
 import (
 	"fmt"
 	"gct/src/config"
+	"net/http"
 	"strings"
 )
 
@@ -24,75 +25,147 @@ var SupportedProviders = []string{
 	"Perplexity",
 	"Lambda",
 	"Groq",
+	"Ollama",
+	"llama.cpp",
+}
+
+type baseURLSetter interface {
+	SetBaseURL(baseURL string)
+}
+
+type baseURLGetter interface {
+	apiBaseURL() string
 }
 
 func NewProvider(cfg *config.Config) (AIProvider, error) {
+	secrets, err := cfg.ResolveSecrets()
+	if err != nil {
+		return nil, &APIError{Provider: cfg.Provider, Kind: ErrAuth, Message: err.Error(), Err: err}
+	}
+	cfg = cfg.WithProvider(secrets)
+
 	providerName := strings.ToLower(strings.ReplaceAll(cfg.Provider, " ", ""))
 
+	timeout := defaultRequestTimeout
+	if IsLocalProvider(cfg.Provider) {
+		timeout = localRequestTimeout
+	}
+	var secretParams []string
+	for _, custom := range cfg.Providers {
+		if custom.AuthName != "" {
+			secretParams = append(secretParams, custom.AuthName)
+		}
+	}
+	client, headers, err := newHTTPClient(cfg.HTTP, timeout, secretParams...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure http client: %w", err)
+	}
+
+	var provider AIProvider
+	if custom, ok := findCustomProvider(cfg.Providers, providerName); ok {
+		provider, err = NewCustomProvider(custom, cfg.APIKey, cfg.Model, client)
+	} else {
+		provider, err = newProvider(cfg, providerName, client)
+		if setter, ok := provider.(baseURLSetter); ok && err == nil && cfg.Endpoint != "" && !cfg.IsFallback() {
+			setter.SetBaseURL(cfg.Endpoint)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if getter, ok := provider.(baseURLGetter); ok && headers != nil {
+		headers.scope(getter.apiBaseURL())
+	}
+	return provider, nil
+}
+
+func newProvider(cfg *config.Config, providerName string, client *http.Client) (AIProvider, error) {
 	switch providerName {
 	case "googleaistudio", "google", "gemini":
-		return NewGoogleProvider(cfg.APIKey, cfg.Model)
+		return NewGoogleProvider(cfg.APIKey, cfg.Model, cfg.SafetySettings, client)
 
 	case "googlevertexai", "vertexai", "vertex":
-		return NewVertexAIProvider(cfg.APIKey, cfg.Model, cfg.GCPProjectID, cfg.GCPRegion)
+		return NewVertexAIProvider(cfg.APIKey, cfg.GCPCredentialsFile, cfg.GCPTokenURL, cfg.Model, cfg.GCPProjectID, cfg.GCPRegion, cfg.SafetySettings, client)
 
 	case "openrouter":
-		return NewOpenRouterProvider(cfg.APIKey, cfg.Model)
+		return NewOpenRouterProvider(cfg.APIKey, cfg.Model, client)
 
 	case "openai", "gpt":
-		return NewOpenAIProvider(cfg.APIKey, cfg.Model)
+		return NewOpenAIProvider(cfg.APIKey, cfg.Model, client)
 
 	case "azureopenai", "azure":
-		return NewAzureProvider(cfg.APIKey, cfg.AzureResourceName, cfg.Model)
+		return NewAzureProvider(cfg.APIKey, cfg.AzureResourceName, cfg.Model, client)
 
 	case "openaicompatible":
-		return NewOpenAICompatibleProvider(cfg.APIKey, cfg.Model, cfg.Endpoint)
+		return NewOpenAICompatibleProvider(cfg.APIKey, cfg.Model, cfg.Endpoint, client)
 
 	case "anthropic", "claude":
-		return NewAnthropicProvider(cfg.APIKey, cfg.Model)
+		return NewAnthropicProvider(cfg.APIKey, cfg.Model, client)
 
 	case "deepseek":
 		endpoint := "https://api.deepseek.com/v1"
 		if cfg.Endpoint != "" {
 			endpoint = cfg.Endpoint
 		}
-		return NewOpenAICompatibleProvider(cfg.APIKey, cfg.Model, endpoint)
+		return NewOpenAICompatibleProvider(cfg.APIKey, cfg.Model, endpoint, client)
 
 	case "mistral":
-		return NewOpenAICompatibleProvider(cfg.APIKey, cfg.Model, "https://api.mistral.ai/v1")
+		return NewOpenAICompatibleProvider(cfg.APIKey, cfg.Model, "https://api.mistral.ai/v1", client)
 
 	case "alibaba", "qwen":
-		return NewOpenAICompatibleProvider(cfg.APIKey, cfg.Model, "https://dashscope.aliyuncs.com/api/v1")
+		return newJSONObjectProvider(NewOpenAICompatibleProvider(cfg.APIKey, cfg.Model, "https://dashscope.aliyuncs.com/api/v1", client))
 
 	case "huggingface", "hf":
-		return NewHuggingFaceProvider(cfg.APIKey, cfg.Model)
+		return NewHuggingFaceProvider(cfg.APIKey, cfg.Model, client)
 
 	case "amazonbedrock", "bedrock", "amazon", "aws":
-		return NewBedrockProvider(cfg.AWSAccessKeyID, cfg.AWSSecretAccessKey, cfg.AWSRegion, cfg.Model)
+		return NewBedrockProvider(cfg.AWSAccessKeyID, cfg.AWSSecretAccessKey, cfg.AWSRegion, cfg.AWSProfile, cfg.AWSRoleARN, cfg.Model, client)
 
 	case "xai", "grok", "x":
-		return NewOpenAICompatibleProvider(cfg.APIKey, cfg.Model, "https://api.x.ai/v1")
+		return newJSONObjectProvider(NewOpenAICompatibleProvider(cfg.APIKey, cfg.Model, "https://api.x.ai/v1", client))
 
 	case "cloudflare", "cf":
 		if cfg.Endpoint == "" {
 			return nil, fmt.Errorf("cloudflare provider requires an 'endpoint' URL in your gct.yaml (e.g. https://api.cloudflare.com/client/v4/accounts/YOUR_ACCOUNT_ID/ai/v1)")
 		}
-		return NewOpenAICompatibleProvider(cfg.APIKey, cfg.Model, cfg.Endpoint)
+		return NewOpenAICompatibleProvider(cfg.APIKey, cfg.Model, cfg.Endpoint, client)
 
 	case "perplexity", "pplx":
-		return NewOpenAICompatibleProvider(cfg.APIKey, cfg.Model, "https://api.perplexity.ai")
+		provider, err := NewOpenAICompatibleProvider(cfg.APIKey, cfg.Model, "https://api.perplexity.ai", client)
+		if err != nil {
+			return nil, err
+		}
+		provider.jsonMode = jsonModeSchema
+		return provider, nil
 
 	case "lambda", "lambdalabs":
-		return NewOpenAICompatibleProvider(cfg.APIKey, cfg.Model, "https://api.lambda-labs.com/v1")
+		return NewOpenAICompatibleProvider(cfg.APIKey, cfg.Model, "https://api.lambda-labs.com/v1", client)
 
 	case "groq":
-		return NewOpenAICompatibleProvider(cfg.APIKey, cfg.Model, "https://api.groq.com/openai/v1")
+		return newJSONObjectProvider(NewOpenAICompatibleProvider(cfg.APIKey, cfg.Model, "https://api.groq.com/openai/v1", client))
+
+	case "ollama":
+		return NewOllamaProvider(cfg.Model, cfg.Endpoint, cfg.OllamaKeepAlive, cfg.OllamaContextSize, client)
+
+	case "llama.cpp", "llamacpp", "llama-cpp":
+		return NewLlamaCppProvider(cfg.APIKey, cfg.Model, cfg.Endpoint, client)
+
+	case "fake":
+		return NewFakeProvider(cfg.Model, cfg.FakeScript)
 
 	default:
 		return nil, fmt.Errorf(
-			"unsupported AI provider: '%s'. Supported providers are: %s",
+			"unsupported AI provider: '%s'. Supported providers are: %s, or one defined under 'providers' in gct.yaml",
 			cfg.Provider,
 			strings.Join(SupportedProviders, ", "),
 		)
 	}
 }
+
+func newJSONObjectProvider(provider *OpenAICompatibleProvider, err error) (*OpenAICompatibleProvider, error) {
+	if err != nil {
+		return nil, err
+	}
+	provider.jsonMode = jsonModeObject
+	return provider, nil
+}
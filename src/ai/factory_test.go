@@ -0,0 +1,52 @@
+package ai
+
+import (
+	"errors"
+	"gct/src/config"
+	"testing"
+)
+
+func TestNewProviderEndpointOverride(t *testing.T) {
+	cfg := &config.Config{
+		ProviderConfig: config.ProviderConfig{Provider: "OpenAI", Model: "gpt-4o", APIKey: "key", Endpoint: "http://127.0.0.1:9/v1"},
+		Fallbacks:      []config.ProviderConfig{{Provider: "OpenAI", Model: "gpt-4o-mini", APIKey: "key", Endpoint: "http://127.0.0.1:9/v1"}},
+		Providers:      []config.CustomProviderConfig{{Name: "gateway", BaseURL: "https://gateway.example.com/v1"}},
+	}
+
+	chain := cfg.ProviderChain()
+	primary, err := NewProvider(cfg.WithProvider(chain[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := primary.(*OpenAIProvider).baseURL; got != "http://127.0.0.1:9/v1" {
+		t.Errorf("primary base URL = %q, want the endpoint override", got)
+	}
+
+	fallback, err := NewProvider(cfg.WithProvider(chain[1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fallback.(*OpenAIProvider).baseURL; got != openAIBaseURL {
+		t.Errorf("fallback base URL = %q, want %q", got, openAIBaseURL)
+	}
+
+	custom, err := NewProvider(cfg.WithProvider(config.ProviderConfig{Provider: "gateway", Model: "m", APIKey: "key", Endpoint: "http://127.0.0.1:9/v1"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := custom.(*OpenAICompatibleProvider).baseURL; got != "https://gateway.example.com/v1" {
+		t.Errorf("custom provider base URL = %q, want its base_url", got)
+	}
+}
+
+func TestNewCustomProviderRequiresKey(t *testing.T) {
+	custom := config.CustomProviderConfig{Name: "gateway", BaseURL: "https://gateway.example.com/v1"}
+	if _, err := NewCustomProvider(custom, "", "m", nil); !errors.Is(err, ErrAuth) {
+		t.Errorf("missing key: got %v, want ErrAuth", err)
+	}
+
+	custom.Auth = authNone
+	if _, err := NewCustomProvider(custom, "", "m", nil); err != nil {
+		t.Errorf("auth: none should not need a key: %v", err)
+	}
+}
@@ -0,0 +1,225 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	fakeDefaultModel    = "fake-model"
+	fakeDefaultResponse = "This is a scripted response from the fake provider."
+)
+
+type FakeProvider struct {
+	model  string
+	script *fakeScript
+}
+
+type fakeReply struct {
+	Match        string `yaml:"match,omitempty"`
+	Response     string `yaml:"response,omitempty"`
+	Reasoning    string `yaml:"reasoning,omitempty"`
+	Status       int    `yaml:"status,omitempty"`
+	Error        string `yaml:"error,omitempty"`
+	Truncated    bool   `yaml:"truncated,omitempty"`
+	Times        int    `yaml:"times,omitempty"`
+	InputTokens  int    `yaml:"input_tokens,omitempty"`
+	OutputTokens int    `yaml:"output_tokens,omitempty"`
+
+	pattern *regexp.Regexp
+	used    int
+}
+
+type fakeScript struct {
+	mu      sync.Mutex
+	replies []*fakeReply
+}
+
+var (
+	fakeScriptsMu sync.Mutex
+	fakeScripts   = map[string]*fakeScript{}
+)
+
+func NewFakeProvider(modelName, scriptPath string) (*FakeProvider, error) {
+	if modelName == "" {
+		modelName = fakeDefaultModel
+	}
+
+	provider := &FakeProvider{model: modelName, script: &fakeScript{}}
+	if scriptPath != "" {
+		script, err := loadFakeScript(scriptPath)
+		if err != nil {
+			return nil, err
+		}
+		provider.script = script
+	}
+	return provider, nil
+}
+
+func loadFakeScript(path string) (*fakeScript, error) {
+	fakeScriptsMu.Lock()
+	defer fakeScriptsMu.Unlock()
+
+	if script, ok := fakeScripts[path]; ok {
+		return script, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fake provider script: %w", err)
+	}
+
+	var replies []*fakeReply
+	if err := yaml.Unmarshal(data, &replies); err != nil {
+		return nil, fmt.Errorf("failed to parse fake provider script %s: %w", path, err)
+	}
+	for i, reply := range replies {
+		if reply.Match == "" {
+			continue
+		}
+		reply.pattern, err = regexp.Compile(reply.Match)
+		if err != nil {
+			return nil, fmt.Errorf("invalid 'match' pattern in reply %d of %s: %w", i+1, path, err)
+		}
+	}
+
+	script := &fakeScript{replies: replies}
+	fakeScripts[path] = script
+	return script, nil
+}
+
+func (s *fakeScript) next(prompt string) *fakeReply {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, reply := range s.replies {
+		if reply.Times > 0 && reply.used >= reply.Times {
+			continue
+		}
+		if reply.pattern != nil && !reply.pattern.MatchString(prompt) {
+			continue
+		}
+		reply.used++
+		return reply
+	}
+	return nil
+}
+
+func (p *FakeProvider) reply(req Request) (*Response, bool, error) {
+	prompt := ""
+	for _, msg := range req.Messages {
+		if msg.Role == RoleUser {
+			prompt = msg.Content
+		}
+	}
+
+	reply := p.script.next(prompt)
+	if reply == nil {
+		text := fakeDefaultResponse
+		if req.Options.Schema != nil {
+			data, _ := json.Marshal(exampleFromSchema(req.Options.Schema.Schema, ""))
+			text = string(data)
+		}
+		return &Response{Text: text}, false, nil
+	}
+
+	if reply.Status != 0 || reply.Error != "" {
+		message := reply.Error
+		if message == "" {
+			message = fmt.Sprintf("scripted status %d", reply.Status)
+		}
+		return nil, false, newAPIError("Fake", p.model, reply.Status, nil, fmt.Sprintf("fake api error (%d): %s", reply.Status, message))
+	}
+
+	return &Response{
+		Text:      reply.Response,
+		Reasoning: reply.Reasoning,
+		Usage:     Usage{InputTokens: reply.InputTokens, OutputTokens: reply.OutputTokens},
+	}, reply.Truncated, nil
+}
+
+func (p *FakeProvider) Generate(ctx context.Context, req Request) (*Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	scripted, truncated, err := p.reply(req)
+	if err != nil {
+		return nil, err
+	}
+
+	result := newReasoningResponse(scripted.Text, scripted.Reasoning, scripted.Usage, thinkTagsFor(p.model, true))
+	if truncated {
+		return nil, &TruncatedError{Provider: "Fake", Reason: "MAX_TOKENS", Response: result}
+	}
+	if result.Text == "" {
+		return nil, fmt.Errorf("received an empty or invalid response from the fake provider")
+	}
+	return result, nil
+}
+
+func (p *FakeProvider) GenerateStream(ctx context.Context, req Request, onChunk StreamHandler) (*Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	scripted, truncated, err := p.reply(req)
+	if err != nil {
+		return nil, err
+	}
+
+	stream := newReasoningStream(ctx, onChunk, thinkTagsFor(p.model, true))
+	stream.Reasoning(scripted.Reasoning)
+	for _, chunk := range strings.SplitAfter(scripted.Text, " ") {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		stream.Text(chunk)
+	}
+
+	result := stream.Response(scripted.Usage)
+	if truncated {
+		return nil, &TruncatedError{Provider: "Fake", Reason: "MAX_TOKENS", Response: result}
+	}
+	if result.Text == "" {
+		return nil, fmt.Errorf("received an empty or invalid response from the fake provider")
+	}
+	return result, nil
+}
+
+func (p *FakeProvider) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	models := []ModelInfo{{ID: fakeDefaultModel, Name: "Fake model"}}
+	if p.model != fakeDefaultModel && p.model != modelListingPlaceholder {
+		models = append(models, ModelInfo{ID: p.model, Name: "Fake model"})
+	}
+	return models, nil
+}
+
+func exampleFromSchema(schema map[string]interface{}, name string) interface{} {
+	switch schema["type"] {
+	case "object":
+		properties, _ := schema["properties"].(map[string]interface{})
+		object := make(map[string]interface{}, len(properties))
+		for property, value := range properties {
+			if propertySchema, ok := value.(map[string]interface{}); ok {
+				object[property] = exampleFromSchema(propertySchema, property)
+			}
+		}
+		return object
+	case "array":
+		return []interface{}{}
+	case "boolean":
+		return false
+	case "number", "integer":
+		return 0
+	default:
+		return strings.TrimSpace("fake " + name)
+	}
+}
@@ -0,0 +1,54 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gct/src/config"
+)
+
+func newFakeTestProvider(t *testing.T, script string) AIProvider {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "replies.yaml")
+	if err := os.WriteFile(path, []byte(script), 0600); err != nil {
+		t.Fatal(err)
+	}
+	provider, err := NewProvider(&config.Config{ProviderConfig: config.ProviderConfig{Provider: "fake", FakeScript: path}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider
+}
+
+func TestFakeProviderScript(t *testing.T) {
+	provider := newFakeTestProvider(t, `
+- match: "changelog"
+  response: "## Added"
+- status: 429
+  error: "slow down"
+  times: 1
+- response: "second try"
+`)
+	ctx := context.Background()
+
+	resp, err := provider.Generate(ctx, Request{Messages: UserPrompt("write a changelog")})
+	if err != nil || resp.Text != "## Added" {
+		t.Fatalf("matched reply: %v, %+v", err, resp)
+	}
+
+	_, err = provider.Generate(ctx, Request{Messages: UserPrompt("explain")})
+	if !errors.Is(err, ErrRateLimited) || !ShouldFallback(err) {
+		t.Fatalf("expected a rate limit error, got %v", err)
+	}
+
+	var chunks []string
+	resp, err = provider.GenerateStream(ctx, Request{Messages: UserPrompt("explain")}, func(chunk string) {
+		chunks = append(chunks, chunk)
+	})
+	if err != nil || resp.Text != "second try" || len(chunks) != 2 {
+		t.Fatalf("streamed reply: %v, %+v, %q", err, resp, chunks)
+	}
+}
@@ -0,0 +1,265 @@
+package ai
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+var fixtureSecretParams = []string{"key", "api_key", "apikey", "access_token", "token"}
+
+var fixtureSecretFields = []string{"access_token", "id_token", "refresh_token"}
+
+var fixtureSkippedHeaders = []string{"Set-Cookie", "Date"}
+
+var errFixtureNotFound = errors.New("no recorded fixture")
+
+type httpFixture struct {
+	Request  httpFixtureRequest  `json:"request"`
+	Response httpFixtureResponse `json:"response"`
+}
+
+type httpFixtureRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+type httpFixtureResponse struct {
+	Status     int                 `json:"status"`
+	Header     map[string][]string `json:"header,omitempty"`
+	Body       string              `json:"body,omitempty"`
+	BodyBase64 string              `json:"body_base64,omitempty"`
+}
+
+type fixtureTransport struct {
+	base         http.RoundTripper
+	dir          string
+	record       bool
+	secretParams []string
+}
+
+func newFixtureTransport(base http.RoundTripper, recordDir, replayDir string, secretParams []string) (http.RoundTripper, error) {
+	switch {
+	case recordDir != "" && replayDir != "":
+		return nil, fmt.Errorf("http.record and http.replay cannot be used together")
+	case recordDir != "":
+		if err := os.MkdirAll(recordDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create http.record directory: %w", err)
+		}
+		return &fixtureTransport{base: base, dir: recordDir, record: true, secretParams: secretParams}, nil
+	case replayDir != "":
+		if info, err := os.Stat(replayDir); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("http.replay directory %s does not exist", replayDir)
+		}
+		return &fixtureTransport{dir: replayDir, secretParams: secretParams}, nil
+	}
+	return base, nil
+}
+
+func (t *fixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	if isFormRequest(req) {
+		body = nil
+	}
+	fixtureURL := redactFixtureURL(req.URL, t.secretParams)
+	path := filepath.Join(t.dir, fixtureKey(req.Method, fixtureURL, body)+".json")
+
+	if !t.record {
+		return replayFixture(req, path, fixtureURL)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	fixture := httpFixture{
+		Request: httpFixtureRequest{Method: req.Method, URL: fixtureURL, Body: string(body)},
+		Response: httpFixtureResponse{
+			Status: resp.StatusCode,
+			Header: fixtureHeader(resp.Header),
+		},
+	}
+	resp.Body = &fixtureRecorder{ReadCloser: resp.Body, fixture: fixture, path: path}
+	return resp, nil
+}
+
+type fixtureRecorder struct {
+	io.ReadCloser
+	fixture httpFixture
+	path    string
+	body    bytes.Buffer
+	done    bool
+}
+
+func (r *fixtureRecorder) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.body.Write(p[:n])
+	if err == io.EOF && !r.done {
+		r.done = true
+		if saveErr := r.save(); saveErr != nil {
+			return n, saveErr
+		}
+	}
+	return n, err
+}
+
+func (r *fixtureRecorder) Close() error {
+	if !r.done {
+		r.done = true
+		if _, err := r.body.ReadFrom(r.ReadCloser); err == nil {
+			if err := r.save(); err != nil {
+				_ = r.ReadCloser.Close()
+				return err
+			}
+		}
+	}
+	return r.ReadCloser.Close()
+}
+
+func (r *fixtureRecorder) save() error {
+	respBody := r.body.Bytes()
+	if utf8.Valid(respBody) {
+		r.fixture.Response.Body = redactFixtureBody(respBody)
+	} else {
+		r.fixture.Response.BodyBase64 = base64.StdEncoding.EncodeToString(respBody)
+	}
+
+	data, err := json.MarshalIndent(r.fixture, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode http fixture: %w", err)
+	}
+	if err := os.WriteFile(r.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write http fixture: %w", err)
+	}
+	return nil
+}
+
+func replayFixture(req *http.Request, path, fixtureURL string) (*http.Response, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w for %s %s (record one with http.record)", errFixtureNotFound, req.Method, fixtureURL)
+	}
+
+	var fixture httpFixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("failed to parse http fixture %s: %w", path, err)
+	}
+
+	body := []byte(fixture.Response.Body)
+	if fixture.Response.BodyBase64 != "" {
+		body, err = base64.StdEncoding.DecodeString(fixture.Response.BodyBase64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode http fixture %s: %w", path, err)
+		}
+	}
+
+	header := http.Header{}
+	for key, values := range fixture.Response.Header {
+		header[http.CanonicalHeaderKey(key)] = values
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", fixture.Response.Status, http.StatusText(fixture.Response.Status)),
+		StatusCode:    fixture.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+func fixtureKey(method, fixtureURL string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method + "\n" + fixtureURL + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func redactFixtureURL(u *url.URL, secretParams []string) string {
+	redacted := *u
+	query := redacted.Query()
+	for _, param := range slices.Concat(fixtureSecretParams, secretParams) {
+		if query.Has(param) {
+			query.Set(param, "REDACTED")
+		}
+	}
+	redacted.RawQuery = query.Encode()
+	return redacted.String()
+}
+
+func redactFixtureBody(body []byte) string {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil || decoder.More() || !redactSecretFields(decoded) {
+		return string(body)
+	}
+	data, err := json.Marshal(decoded)
+	if err != nil {
+		return string(body)
+	}
+	return string(data)
+}
+
+func redactSecretFields(value interface{}) bool {
+	redacted := false
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if _, ok := field.(string); ok && slices.Contains(fixtureSecretFields, strings.ToLower(key)) {
+				v[key] = "REDACTED"
+				redacted = true
+				continue
+			}
+			redacted = redactSecretFields(field) || redacted
+		}
+	case []interface{}:
+		for _, item := range v {
+			redacted = redactSecretFields(item) || redacted
+		}
+	}
+	return redacted
+}
+
+func fixtureHeader(header http.Header) map[string][]string {
+	kept := make(map[string][]string, len(header))
+	for key, values := range header {
+		if slices.ContainsFunc(fixtureSkippedHeaders, func(skipped string) bool { return strings.EqualFold(key, skipped) }) {
+			continue
+		}
+		kept[key] = values
+	}
+	return kept
+}
+
+func isFormRequest(req *http.Request) bool {
+	return strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded")
+}
@@ -0,0 +1,130 @@
+package ai
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gct/src/config"
+)
+
+func readFixtures(t *testing.T, dir string) string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) == 0 {
+		t.Fatal("no fixtures were recorded")
+	}
+	var all strings.Builder
+	for _, entry := range entries {
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		all.Write(data)
+	}
+	return all.String()
+}
+
+func TestFixturesRedactTokenResponses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"access_token":"ya29.live-token","id_token":"eyJ.live","expires_in":3599,"token_type":"Bearer"}`)
+	}))
+
+	dir := t.TempDir()
+	client, _, err := newHTTPClient(config.HTTPConfig{Record: dir}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	form := url.Values{"grant_type": {"refresh_token"}, "refresh_token": {"1//live-refresh"}}
+	resp, err := client.PostForm(server.URL+"/token", form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	server.Close()
+
+	if !strings.Contains(string(body), "ya29.live-token") {
+		t.Fatalf("the caller should get the real token, got %s", body)
+	}
+	fixtures := readFixtures(t, dir)
+	for _, secret := range []string{"ya29.live-token", "eyJ.live", "1//live-refresh"} {
+		if strings.Contains(fixtures, secret) {
+			t.Fatalf("fixture contains %q:\n%s", secret, fixtures)
+		}
+	}
+
+	replay, _, err := newHTTPClient(config.HTTPConfig{Replay: dir}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = replay.PostForm(server.URL+"/token", form)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if !strings.Contains(string(body), `"access_token":"REDACTED"`) || !strings.Contains(string(body), `"expires_in":3599`) {
+		t.Fatalf("unexpected replayed body %s", body)
+	}
+}
+
+func TestFixturesRedactCustomProviderAuthParam(t *testing.T) {
+	var gotKey string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotKey = r.URL.Query().Get("api-token")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"choices":[{"message":{"role":"assistant","content":"recorded answer"},"finish_reason":"stop"}],"usage":{"prompt_tokens":5,"completion_tokens":2}}`)
+	}))
+
+	dir := t.TempDir()
+	cfg := &config.Config{
+		ProviderConfig: config.ProviderConfig{Provider: "Acme", Model: "acme-1", APIKey: "sk-live-secret"},
+		Providers: []config.CustomProviderConfig{{
+			Name: "Acme", BaseURL: server.URL + "/v1", Dialect: "openai-chat", Auth: "query", AuthName: "api-token",
+		}},
+		HTTP: config.HTTPConfig{Record: dir},
+	}
+	req := Request{Messages: UserPrompt("hello")}
+
+	provider, err := NewProvider(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := provider.Generate(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+	if gotKey != "sk-live-secret" || resp.Text != "recorded answer" {
+		t.Fatalf("key %q, text %q", gotKey, resp.Text)
+	}
+	if fixtures := readFixtures(t, dir); strings.Contains(fixtures, "sk-live-secret") {
+		t.Fatalf("fixture contains the API key:\n%s", fixtures)
+	}
+
+	cfg.HTTP = config.HTTPConfig{Replay: dir}
+	cfg.APIKey = "sk-other-key"
+	provider, err = NewProvider(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = provider.Generate(context.Background(), req)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if resp.Text != "recorded answer" {
+		t.Fatalf("replayed text %q", resp.Text)
+	}
+}
@@ -0,0 +1,248 @@
+package ai
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+)
+
+const (
+	gcpDefaultTokenURL = "https://oauth2.googleapis.com/token"
+	gcpCloudScope      = "https://www.googleapis.com/auth/cloud-platform"
+	gcpTokenLifetime   = time.Hour
+	gcpTokenEarlyRenew = time.Minute
+)
+
+type gcpCredentialsFile struct {
+	Type string `json:"type"`
+
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	PrivateKeyID string `json:"private_key_id"`
+	TokenURI     string `json:"token_uri"`
+
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type gcpTokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	ExpiresIn        int    `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+type gcpTokenSource struct {
+	client   *http.Client
+	creds    gcpCredentialsFile
+	tokenURL string
+	key      *rsa.PrivateKey
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+func findGCPCredentialsFile(path string) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+	if env := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"); env != "" {
+		return env, nil
+	}
+
+	var adcPath string
+	if runtime.GOOS == "windows" {
+		adcPath = filepath.Join(os.Getenv("APPDATA"), "gcloud", "application_default_credentials.json")
+	} else {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		adcPath = filepath.Join(homeDir, ".config", "gcloud", "application_default_credentials.json")
+	}
+	if _, err := os.Stat(adcPath); err != nil {
+		return "", fmt.Errorf("no Google credentials found: set gcp_credentials_file, GOOGLE_APPLICATION_CREDENTIALS, or run 'gcloud auth application-default login'")
+	}
+	return adcPath, nil
+}
+
+func newGCPTokenSource(credentialsPath, tokenURL string, client *http.Client) (*gcpTokenSource, error) {
+	path, err := findGCPCredentialsFile(credentialsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Google credentials file %s: %w", path, err)
+	}
+
+	var creds gcpCredentialsFile
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return nil, fmt.Errorf("failed to parse Google credentials file %s: %w", path, err)
+	}
+
+	source := &gcpTokenSource{
+		client:   client,
+		creds:    creds,
+		tokenURL: tokenURL,
+	}
+
+	switch creds.Type {
+	case "service_account":
+		if creds.ClientEmail == "" || creds.PrivateKey == "" {
+			return nil, fmt.Errorf("service account file %s is missing client_email or private_key", path)
+		}
+		source.key, err = parseRSAPrivateKey(creds.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid private key in %s: %w", path, err)
+		}
+		if source.tokenURL == "" {
+			source.tokenURL = creds.TokenURI
+		}
+	case "authorized_user":
+		if creds.RefreshToken == "" {
+			return nil, fmt.Errorf("credentials file %s has no refresh_token; run 'gcloud auth application-default login' again", path)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported Google credentials type '%s' in %s (expected service_account or authorized_user)", creds.Type, path)
+	}
+
+	if source.tokenURL == "" {
+		source.tokenURL = gcpDefaultTokenURL
+	}
+	return source, nil
+}
+
+func parseRSAPrivateKey(pemKey string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(pemKey))
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("private key is not an RSA key")
+		}
+		return rsaKey, nil
+	}
+	return x509.ParsePKCS1PrivateKey(block.Bytes)
+}
+
+func (s *gcpTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && time.Now().Add(gcpTokenEarlyRenew).Before(s.expires) {
+		return s.token, nil
+	}
+
+	form := url.Values{}
+	if s.creds.Type == "service_account" {
+		assertion, err := s.signedJWT()
+		if err != nil {
+			return "", &APIError{Provider: "Google Vertex AI", Kind: ErrAuth, Message: fmt.Sprintf("failed to sign the Google access token request: %v", err), Err: err}
+		}
+		form.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+		form.Set("assertion", assertion)
+	} else {
+		form.Set("grant_type", "refresh_token")
+		form.Set("client_id", s.creds.ClientID)
+		form.Set("client_secret", s.creds.ClientSecret)
+		form.Set("refresh_token", s.creds.RefreshToken)
+	}
+
+	headers := http.Header{}
+	headers.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := sendRequest(ctx, s.client, "POST", s.tokenURL, headers, []byte(form.Encode()))
+	if err != nil {
+		return "", requestError("Google Vertex AI", fmt.Errorf("failed to request a Google access token: %w", err))
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", requestError("Google Vertex AI", fmt.Errorf("failed to read Google token response: %w", err))
+	}
+
+	var tokenResp gcpTokenResponse
+	_ = json.Unmarshal(respBody, &tokenResp)
+	if resp.StatusCode != http.StatusOK || tokenResp.AccessToken == "" {
+		message := tokenResp.ErrorDescription
+		if message == "" {
+			message = tokenResp.Error
+		}
+		if message == "" {
+			message = string(respBody)
+		}
+		apiErr := newAPIError("Google Vertex AI", "", resp.StatusCode, respBody, fmt.Sprintf("failed to obtain a Google access token: %s", message))
+		if resp.StatusCode < http.StatusInternalServerError {
+			apiErr.Kind = ErrAuth
+		}
+		return "", apiErr
+	}
+
+	lifetime := time.Duration(tokenResp.ExpiresIn) * time.Second
+	if lifetime <= 0 {
+		lifetime = gcpTokenLifetime
+	}
+	s.token = tokenResp.AccessToken
+	s.expires = time.Now().Add(lifetime)
+	return s.token, nil
+}
+
+func (s *gcpTokenSource) signedJWT() (string, error) {
+	now := time.Now()
+	header := map[string]string{"alg": "RS256", "typ": "JWT"}
+	if s.creds.PrivateKeyID != "" {
+		header["kid"] = s.creds.PrivateKeyID
+	}
+	claims := map[string]interface{}{
+		"iss":   s.creds.ClientEmail,
+		"scope": gcpCloudScope,
+		"aud":   s.tokenURL,
+		"iat":   now.Unix(),
+		"exp":   now.Add(gcpTokenLifetime).Unix(),
+	}
+
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode jwt header: %w", err)
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode jwt claims: %w", err)
+	}
+
+	encoding := base64.RawURLEncoding
+	unsigned := encoding.EncodeToString(headerJSON) + "." + encoding.EncodeToString(claimsJSON)
+
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign jwt: %w", err)
+	}
+
+	return unsigned + "." + encoding.EncodeToString(signature), nil
+}
@@ -0,0 +1,210 @@
+package ai
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+var geminiHarmCategories = map[string]string{
+	"harassment":        "HARM_CATEGORY_HARASSMENT",
+	"hate_speech":       "HARM_CATEGORY_HATE_SPEECH",
+	"sexually_explicit": "HARM_CATEGORY_SEXUALLY_EXPLICIT",
+	"dangerous_content": "HARM_CATEGORY_DANGEROUS_CONTENT",
+	"civic_integrity":   "HARM_CATEGORY_CIVIC_INTEGRITY",
+}
+
+var geminiBlockThresholds = map[string]string{
+	"none":                   "BLOCK_NONE",
+	"block_none":             "BLOCK_NONE",
+	"only_high":              "BLOCK_ONLY_HIGH",
+	"block_only_high":        "BLOCK_ONLY_HIGH",
+	"medium":                 "BLOCK_MEDIUM_AND_ABOVE",
+	"block_medium_and_above": "BLOCK_MEDIUM_AND_ABOVE",
+	"low":                    "BLOCK_LOW_AND_ABOVE",
+	"block_low_and_above":    "BLOCK_LOW_AND_ABOVE",
+	"off":                    "OFF",
+}
+
+var geminiBlockDetails = map[string]string{
+	"SAFETY":             "Relax 'safety_settings' in gct.yaml if the content is harmless",
+	"RECITATION":         "The answer repeated too much existing material, such as licensed code; try again or reduce the input",
+	"BLOCKLIST":          "The content contains terms on the provider's blocklist",
+	"PROHIBITED_CONTENT": "The content may break the provider's usage policies",
+	"SPII":               "The content appears to contain sensitive personal information",
+}
+
+type geminiSafetySetting struct {
+	Category  string `json:"category"`
+	Threshold string `json:"threshold"`
+}
+
+type geminiSafetyRating struct {
+	Category    string `json:"category"`
+	Probability string `json:"probability"`
+	Blocked     bool   `json:"blocked,omitempty"`
+}
+
+type geminiPromptFeedback struct {
+	BlockReason   string               `json:"blockReason"`
+	SafetyRatings []geminiSafetyRating `json:"safetyRatings"`
+}
+
+type geminiRequest struct {
+	SystemInstruction *geminiContent        `json:"systemInstruction,omitempty"`
+	Contents          []geminiContent       `json:"contents"`
+	SafetySettings    []geminiSafetySetting `json:"safetySettings,omitempty"`
+	GenerationConfig  *geminiGenConfig      `json:"generationConfig,omitempty"`
+}
+
+type geminiGenConfig struct {
+	MaxOutputTokens int      `json:"maxOutputTokens,omitempty"`
+	Temperature     *float64 `json:"temperature,omitempty"`
+	TopP            *float64 `json:"topP,omitempty"`
+	StopSequences   []string `json:"stopSequences,omitempty"`
+
+	ResponseMimeType string                 `json:"responseMimeType,omitempty"`
+	ResponseSchema   map[string]interface{} `json:"responseSchema,omitempty"`
+
+	ThinkingConfig *geminiThinkingConfig `json:"thinkingConfig,omitempty"`
+}
+
+type geminiThinkingConfig struct {
+	ThinkingBudget  int  `json:"thinkingBudget"`
+	IncludeThoughts bool `json:"includeThoughts"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiPart struct {
+	Text string `json:"text"`
+}
+
+type geminiUsageMetadata struct {
+	PromptTokenCount     int `json:"promptTokenCount"`
+	CandidatesTokenCount int `json:"candidatesTokenCount"`
+}
+
+func (u *geminiUsageMetadata) toUsage() Usage {
+	if u == nil {
+		return Usage{}
+	}
+	return Usage{InputTokens: u.PromptTokenCount, OutputTokens: u.CandidatesTokenCount}
+}
+
+type geminiResponse struct {
+	Candidates []struct {
+		Content struct {
+			Parts []struct {
+				Text    string `json:"text"`
+				Thought bool   `json:"thought,omitempty"`
+			} `json:"parts"`
+			Role string `json:"role"`
+		} `json:"content"`
+		FinishReason  string               `json:"finishReason"`
+		SafetyRatings []geminiSafetyRating `json:"safetyRatings"`
+	} `json:"candidates"`
+	PromptFeedback *geminiPromptFeedback `json:"promptFeedback,omitempty"`
+	UsageMetadata  *geminiUsageMetadata  `json:"usageMetadata,omitempty"`
+	Error          *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	} `json:"error,omitempty"`
+}
+
+func newGeminiRequest(req Request, safety []geminiSafetySetting, defaultMaxTokens int) geminiRequest {
+	system, conversation := splitSystemMessages(req.Messages)
+
+	payload := geminiRequest{SafetySettings: safety}
+	if system != "" {
+		payload.SystemInstruction = &geminiContent{Parts: []geminiPart{{Text: system}}}
+	}
+	opts := req.Options
+	maxTokens := opts.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = defaultMaxTokens
+	}
+	budget := geminiThinkingBudget(opts)
+	if maxTokens > 0 || opts.Temperature != nil || opts.TopP != nil || len(opts.StopSequences) > 0 || opts.Schema != nil || budget > 0 {
+		payload.GenerationConfig = &geminiGenConfig{
+			MaxOutputTokens: maxTokens,
+			Temperature:     opts.Temperature,
+			TopP:            opts.TopP,
+			StopSequences:   opts.StopSequences,
+		}
+		if opts.Schema != nil {
+			payload.GenerationConfig.ResponseMimeType = "application/json"
+			payload.GenerationConfig.ResponseSchema = geminiSchema(opts.Schema.Schema)
+		}
+		if budget > 0 {
+			payload.GenerationConfig.ThinkingConfig = &geminiThinkingConfig{ThinkingBudget: budget, IncludeThoughts: true}
+		}
+	}
+	for _, m := range conversation {
+		payload.Contents = append(payload.Contents, geminiContent{
+			Role:  geminiRole(m.Role),
+			Parts: []geminiPart{{Text: m.Content}},
+		})
+	}
+	return payload
+}
+
+func newGeminiSafetySettings(settings map[string]string) ([]geminiSafetySetting, error) {
+	var result []geminiSafetySetting
+	for category, threshold := range settings {
+		key := strings.ToLower(strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(category)), "HARM_CATEGORY_"))
+		harmCategory, ok := geminiHarmCategories[key]
+		if !ok {
+			return nil, fmt.Errorf("unknown safety_settings category '%s': use harassment, hate_speech, sexually_explicit, dangerous_content or civic_integrity", category)
+		}
+		blockThreshold, ok := geminiBlockThresholds[strings.ToLower(strings.TrimSpace(threshold))]
+		if !ok {
+			return nil, fmt.Errorf("unknown safety_settings threshold '%s' for %s: use none, only_high, medium, low or off", threshold, category)
+		}
+		result = append(result, geminiSafetySetting{Category: harmCategory, Threshold: blockThreshold})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Category < result[j].Category })
+	return result, nil
+}
+
+func geminiFinishError(provider, finishReason string, ratings []geminiSafetyRating, feedback *geminiPromptFeedback, partial *Response) error {
+	if feedback != nil && feedback.BlockReason != "" {
+		return &BlockedError{
+			Provider:   provider,
+			StatusCode: http.StatusOK,
+			Reason:     feedback.BlockReason,
+			Categories: geminiBlockedCategories(feedback.SafetyRatings),
+			Detail:     geminiBlockDetails[feedback.BlockReason],
+			Prompt:     true,
+		}
+	}
+
+	switch finishReason {
+	case "", "STOP", "FINISH_REASON_UNSPECIFIED":
+		return nil
+	case "MAX_TOKENS":
+		return &TruncatedError{Provider: provider, Reason: finishReason, Response: partial}
+	}
+	return &BlockedError{
+		Provider:   provider,
+		StatusCode: http.StatusOK,
+		Reason:     finishReason,
+		Categories: geminiBlockedCategories(ratings),
+		Detail:     geminiBlockDetails[finishReason],
+	}
+}
+
+func geminiBlockedCategories(ratings []geminiSafetyRating) []string {
+	var categories []string
+	for _, rating := range ratings {
+		if rating.Blocked || rating.Probability == "HIGH" || rating.Probability == "MEDIUM" {
+			categories = append(categories, strings.TrimPrefix(rating.Category, "HARM_CATEGORY_"))
+		}
+	}
+	return categories
+}
@@ -4,95 +4,255 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
-	"time"
+	"net/url"
+	"slices"
+	"strings"
 )
 
+const googleAPIBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+
 type GoogleProvider struct {
 	client  *http.Client
 	apiKey  string
 	model   string
 	baseURL string
+	safety  []geminiSafetySetting
 }
 
-type googleRESTRequest struct {
-	Contents []googleContent `json:"contents"`
+func NewGoogleProvider(apiKey, modelName string, safetySettings map[string]string, client *http.Client) (*GoogleProvider, error) {
+	if apiKey == "" {
+		return nil, missingCredentials("Google AI Studio", "google AI Studio API key is required")
+	}
+	safety, err := newGeminiSafetySettings(safetySettings)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GoogleProvider{
+		client:  httpClientOrDefault(client, defaultRequestTimeout),
+		apiKey:  apiKey,
+		model:   modelName,
+		baseURL: googleAPIBaseURL,
+		safety:  safety,
+	}, nil
 }
 
-type googleContent struct {
-	Parts []googlePart `json:"parts"`
+func (p *GoogleProvider) SetBaseURL(baseURL string) {
+	p.baseURL = strings.TrimSuffix(baseURL, "/")
 }
 
-type googlePart struct {
-	Text string `json:"text"`
+func (p *GoogleProvider) apiBaseURL() string {
+	return p.baseURL
 }
 
-type googleRESTResponse struct {
-	Candidates []struct {
-		Content struct {
-			Parts []struct {
-				Text string `json:"text"`
-			} `json:"parts"`
-			Role string `json:"role"`
-		} `json:"content"`
-	} `json:"candidates"`
-	Error *struct {
-		Code    int    `json:"code"`
-		Message string `json:"message"`
-		Status  string `json:"status"`
-	} `json:"error,omitempty"`
+func (p *GoogleProvider) withKey(endpoint string) string {
+	if p.apiKey == "" {
+		return endpoint
+	}
+	separator := "?"
+	if strings.Contains(endpoint, "?") {
+		separator = "&"
+	}
+	return endpoint + separator + "key=" + p.apiKey
 }
 
-func NewGoogleProvider(apiKey, modelName string) (*GoogleProvider, error) {
-	if apiKey == "" {
-		return nil, fmt.Errorf("google AI Studio API key is required")
+func (p *GoogleProvider) newRequest(req Request) (geminiRequest, http.Header) {
+	payload := newGeminiRequest(req, p.safety, 0)
+
+	headers := http.Header{}
+	headers.Set("Content-Type", "application/json")
+
+	return payload, headers
+}
+
+func geminiThinkingBudget(opts GenerationOptions) int {
+	if opts.ThinkingBudget > 0 {
+		return opts.ThinkingBudget
+	}
+	switch opts.ReasoningEffort {
+	case "minimal", "low":
+		return 1024
+	case "medium":
+		return 8192
+	case "high":
+		return 24576
 	}
+	return 0
+}
 
-	return &GoogleProvider{
-		client: &http.Client{
-			Timeout: 90 * time.Second,
-		},
-		apiKey:  apiKey,
-		model:   modelName,
-		baseURL: "https://generativelanguage.googleapis.com/v1beta/models/",
-	}, nil
+func geminiRole(role Role) string {
+	if role == RoleAssistant {
+		return "model"
+	}
+	return "user"
 }
 
-func (p *GoogleProvider) Generate(ctx context.Context, prompt string) (string, error) {
-	payload := googleRESTRequest{
-		Contents: []googleContent{
-			{
-				Parts: []googlePart{
-					{Text: prompt},
-				},
-			},
-		},
+func (p *GoogleProvider) errorFromResponse(statusCode int, respBody []byte) error {
+	var apiResp geminiResponse
+	if err := json.Unmarshal(respBody, &apiResp); err == nil && apiResp.Error != nil {
+		return newAPIError("Google AI Studio", p.model, statusCode, respBody, fmt.Sprintf("google api error (%d - %s): %s", apiResp.Error.Code, apiResp.Error.Status, apiResp.Error.Message))
 	}
+	return newAPIError("Google AI Studio", p.model, statusCode, respBody, fmt.Sprintf("received non-200 status from google ai: %d", statusCode))
+}
 
-	url := fmt.Sprintf("%s%s:generateContent?key=%s", p.baseURL, p.model, p.apiKey)
-	headers := http.Header{}
-	headers.Set("Content-Type", "application/json")
+func (p *GoogleProvider) Generate(ctx context.Context, req Request) (*Response, error) {
+	payload, headers := p.newRequest(req)
 
+	url := p.withKey(fmt.Sprintf("%s/models/%s:generateContent", p.baseURL, p.model))
 	respBody, statusCode, err := doAPIRequest(ctx, p.client, "POST", url, headers, payload)
 	if err != nil {
-		return "", fmt.Errorf("failed to send request to google ai: %w", err)
+		return nil, requestError("Google AI Studio", fmt.Errorf("failed to send request to google ai: %w", err))
+	}
+
+	if statusCode != http.StatusOK {
+		return nil, p.errorFromResponse(statusCode, respBody)
 	}
 
-	var apiResp googleRESTResponse
+	var apiResp geminiResponse
 	if err := json.Unmarshal(respBody, &apiResp); err != nil {
-		return "", fmt.Errorf("failed to parse google json response: %w", err)
+		return nil, fmt.Errorf("failed to parse google json response: %w", err)
 	}
 
-	if statusCode != http.StatusOK {
-		if apiResp.Error != nil {
-			return "", fmt.Errorf("google api error (%d - %s): %s", apiResp.Error.Code, apiResp.Error.Status, apiResp.Error.Message)
+	if len(apiResp.Candidates) == 0 {
+		if err := geminiFinishError("Google AI Studio", "", nil, apiResp.PromptFeedback, nil); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("received an empty or invalid response from google ai")
+	}
+
+	candidate := apiResp.Candidates[0]
+	var text, thoughts strings.Builder
+	for _, part := range candidate.Content.Parts {
+		if part.Thought {
+			thoughts.WriteString(part.Text)
+			continue
 		}
-		return "", fmt.Errorf("received non-200 status from google ai: %d", statusCode)
+		text.WriteString(part.Text)
+	}
+
+	result := newReasoningResponse(text.String(), thoughts.String(), apiResp.UsageMetadata.toUsage(), thinkTagsFor(p.model, false))
+	if err := geminiFinishError("Google AI Studio", candidate.FinishReason, candidate.SafetyRatings, apiResp.PromptFeedback, result); err != nil {
+		return nil, err
+	}
+	if result.Text == "" {
+		return nil, fmt.Errorf("received an empty or invalid response from google ai")
+	}
+	return result, nil
+}
+
+func (p *GoogleProvider) GenerateStream(ctx context.Context, req Request, onChunk StreamHandler) (*Response, error) {
+	payload, headers := p.newRequest(req)
+
+	url := p.withKey(fmt.Sprintf("%s/models/%s:streamGenerateContent?alt=sse", p.baseURL, p.model))
+	resp, err := doStreamRequest(ctx, p.client, "POST", url, headers, payload)
+	if err != nil {
+		return nil, requestError("Google AI Studio", fmt.Errorf("failed to send request to google ai: %w", err))
 	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
 
-	if len(apiResp.Candidates) == 0 || len(apiResp.Candidates[0].Content.Parts) == 0 {
-		return "", fmt.Errorf("received an empty or invalid response from google ai")
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, p.errorFromResponse(resp.StatusCode, respBody)
 	}
 
-	return apiResp.Candidates[0].Content.Parts[0].Text, nil
+	stream := newReasoningStream(ctx, onChunk, thinkTagsFor(p.model, false))
+	var usage Usage
+	var finishReason string
+	var ratings []geminiSafetyRating
+	var feedback *geminiPromptFeedback
+	err = readSSE(resp.Body, func(_, data string) error {
+		var chunk geminiResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("failed to parse google stream chunk: %w", err)
+		}
+		if chunk.Error != nil {
+			return newAPIError("Google AI Studio", p.model, chunk.Error.Code, []byte(chunk.Error.Status),
+				fmt.Sprintf("google api error (%d - %s): %s", chunk.Error.Code, chunk.Error.Status, chunk.Error.Message))
+		}
+		if chunk.UsageMetadata != nil {
+			usage = chunk.UsageMetadata.toUsage()
+		}
+		if chunk.PromptFeedback != nil {
+			feedback = chunk.PromptFeedback
+		}
+		if len(chunk.Candidates) == 0 {
+			return nil
+		}
+		if reason := chunk.Candidates[0].FinishReason; reason != "" {
+			finishReason, ratings = reason, chunk.Candidates[0].SafetyRatings
+		}
+		for _, part := range chunk.Candidates[0].Content.Parts {
+			if part.Thought {
+				stream.Reasoning(part.Text)
+				continue
+			}
+			stream.Text(part.Text)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, requestError("Google AI Studio", fmt.Errorf("google ai stream failed: %w", err))
+	}
+
+	result := stream.Response(usage)
+	if err := geminiFinishError("Google AI Studio", finishReason, ratings, feedback, result); err != nil {
+		return nil, err
+	}
+	if result.Text == "" {
+		return nil, fmt.Errorf("received an empty or invalid response from google ai")
+	}
+	return result, nil
+}
+
+type googleModelsResponse struct {
+	Models []struct {
+		Name                       string   `json:"name"`
+		DisplayName                string   `json:"displayName"`
+		InputTokenLimit            int      `json:"inputTokenLimit"`
+		SupportedGenerationMethods []string `json:"supportedGenerationMethods"`
+	} `json:"models"`
+	NextPageToken string `json:"nextPageToken"`
+}
+
+func (p *GoogleProvider) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	var models []ModelInfo
+	pageToken := ""
+	for {
+		endpoint := p.withKey(fmt.Sprintf("%s/models?pageSize=1000", p.baseURL))
+		if pageToken != "" {
+			endpoint += "&pageToken=" + url.QueryEscape(pageToken)
+		}
+
+		respBody, statusCode, err := doAPIRequest(ctx, p.client, "GET", endpoint, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list google ai models: %w", err)
+		}
+		if statusCode != http.StatusOK {
+			return nil, p.errorFromResponse(statusCode, respBody)
+		}
+
+		var page googleModelsResponse
+		if err := json.Unmarshal(respBody, &page); err != nil {
+			return nil, fmt.Errorf("failed to parse google ai model list: %w", err)
+		}
+		for _, m := range page.Models {
+			if !slices.Contains(m.SupportedGenerationMethods, "generateContent") {
+				continue
+			}
+			models = append(models, ModelInfo{
+				ID:            strings.TrimPrefix(m.Name, "models/"),
+				Name:          m.DisplayName,
+				ContextWindow: m.InputTokenLimit,
+			})
+		}
+
+		if page.NextPageToken == "" {
+			return models, nil
+		}
+		pageToken = page.NextPageToken
+	}
 }
@@ -6,10 +6,9 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
-	"time"
 )
 
-const huggingFaceAPIBaseURL = "https://api-inference.huggingface.co/models/"
+const huggingFaceAPIBaseURL = "https://api-inference.huggingface.co"
 
 type HuggingFaceProvider struct {
 	client  *http.Client
@@ -19,52 +18,98 @@ type HuggingFaceProvider struct {
 }
 
 type huggingFaceRequest struct {
-	Inputs string `json:"inputs"`
+	Inputs     string                 `json:"inputs"`
+	Parameters *huggingFaceParameters `json:"parameters,omitempty"`
+}
+
+type huggingFaceParameters struct {
+	MaxNewTokens int      `json:"max_new_tokens,omitempty"`
+	Temperature  *float64 `json:"temperature,omitempty"`
+	TopP         *float64 `json:"top_p,omitempty"`
+	Stop         []string `json:"stop,omitempty"`
 }
 
 type huggingFaceResponse []struct {
 	GeneratedText string `json:"generated_text"`
 }
 
-func NewHuggingFaceProvider(apiKey, modelName string) (*HuggingFaceProvider, error) {
+func NewHuggingFaceProvider(apiKey, modelName string, client *http.Client) (*HuggingFaceProvider, error) {
 	if apiKey == "" {
-		return nil, fmt.Errorf("hugging Face API key is required")
+		return nil, missingCredentials("Hugging Face", "hugging Face API key is required")
 	}
 	return &HuggingFaceProvider{
-		client: &http.Client{
-			Timeout: 90 * time.Second,
-		},
+		client:  httpClientOrDefault(client, defaultRequestTimeout),
 		apiKey:  apiKey,
 		model:   modelName,
 		baseURL: huggingFaceAPIBaseURL,
 	}, nil
 }
 
-func (p *HuggingFaceProvider) Generate(ctx context.Context, prompt string) (string, error) {
+func (p *HuggingFaceProvider) SetBaseURL(baseURL string) {
+	p.baseURL = strings.TrimSuffix(baseURL, "/")
+}
+
+func (p *HuggingFaceProvider) apiBaseURL() string {
+	return p.baseURL
+}
+
+func (p *HuggingFaceProvider) Generate(ctx context.Context, req Request) (*Response, error) {
+	prompt := flattenMessages(req.Messages, "User", "Assistant")
+	if len(req.Messages) == 1 && req.Messages[0].Role == RoleUser {
+		prompt = req.Messages[0].Content
+	}
+
 	payload := huggingFaceRequest{Inputs: prompt}
+	opts := req.Options
+	if opts.MaxTokens > 0 || opts.Temperature != nil || opts.TopP != nil || len(opts.StopSequences) > 0 {
+		payload.Parameters = &huggingFaceParameters{
+			MaxNewTokens: opts.MaxTokens,
+			Temperature:  opts.Temperature,
+			TopP:         opts.TopP,
+			Stop:         opts.StopSequences,
+		}
+	}
 
 	headers := http.Header{}
 	headers.Set("Content-Type", "application/json")
 	headers.Set("Authorization", "Bearer "+p.apiKey)
 
-	url := p.baseURL + p.model
+	url := p.baseURL + "/models/" + p.model
 	respBody, statusCode, err := doAPIRequest(ctx, p.client, "POST", url, headers, payload)
 	if err != nil {
-		return "", fmt.Errorf("failed to send request to huggingface: %w", err)
+		return nil, requestError("Hugging Face", fmt.Errorf("failed to send request to huggingface: %w", err))
 	}
 
 	if statusCode != http.StatusOK {
-		return "", fmt.Errorf("received non-200 status from huggingface: %s", string(respBody))
+		return nil, newAPIError("Hugging Face", p.model, statusCode, respBody, fmt.Sprintf("received non-200 status from huggingface: %s", string(respBody)))
 	}
 
 	var apiResp huggingFaceResponse
 	if err := json.Unmarshal(respBody, &apiResp); err != nil {
-		return "", fmt.Errorf("failed to parse huggingface json response: %w", err)
+		return nil, fmt.Errorf("failed to parse huggingface json response: %w", err)
 	}
 
 	if len(apiResp) == 0 || apiResp[0].GeneratedText == "" {
-		return "", fmt.Errorf("received an empty response from huggingface")
+		return nil, fmt.Errorf("received an empty response from huggingface")
+	}
+
+	result := newReasoningResponse(strings.TrimPrefix(apiResp[0].GeneratedText, prompt), "", Usage{}, thinkTagsFor(p.model, true))
+	if result.Text == "" {
+		return nil, fmt.Errorf("received an empty response from huggingface")
 	}
+	return result, nil
+}
 
-	return strings.TrimPrefix(apiResp[0].GeneratedText, prompt), nil
+func (p *HuggingFaceProvider) GenerateStream(ctx context.Context, req Request, onChunk StreamHandler) (*Response, error) {
+	result, err := p.Generate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if onReasoning := reasoningHandlerFrom(ctx); onReasoning != nil && result.Reasoning != "" {
+		onReasoning(result.Reasoning)
+	}
+	if onChunk != nil {
+		onChunk(result.Text)
+	}
+	return result, nil
 }
@@ -0,0 +1,31 @@
+package ai
+
+import (
+	"net/http"
+	"strings"
+)
+
+const llamaCppDefaultBaseURL = "http://localhost:8080/v1"
+
+func NewLlamaCppProvider(apiKey, modelName, baseURL string, client *http.Client) (*OpenAICompatibleProvider, error) {
+	if baseURL == "" {
+		baseURL = llamaCppDefaultBaseURL
+	}
+
+	return &OpenAICompatibleProvider{
+		client:   httpClientOrDefault(client, localRequestTimeout),
+		name:     "llama.cpp",
+		apiKey:   apiKey,
+		model:    modelName,
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		jsonMode: jsonModeSchema,
+	}, nil
+}
+
+func IsLocalProvider(providerName string) bool {
+	switch strings.ToLower(strings.ReplaceAll(providerName, " ", "")) {
+	case "ollama", "llama.cpp", "llamacpp", "llama-cpp":
+		return true
+	}
+	return false
+}
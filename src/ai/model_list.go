@@ -0,0 +1,74 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"gct/src/config"
+	"sort"
+	"strings"
+)
+
+type ModelInfo struct {
+	ID            string
+	Name          string
+	ContextWindow int
+	InputPrice    float64
+	OutputPrice   float64
+}
+
+type ModelLister interface {
+	ListModels(ctx context.Context) ([]ModelInfo, error)
+}
+
+const modelListingPlaceholder = "model-listing"
+
+func ListModels(ctx context.Context, cfg *config.Config) ([]ModelInfo, error) {
+	listingConfig := *cfg
+	if listingConfig.Model == "" {
+		listingConfig.Model = modelListingPlaceholder
+	}
+
+	provider, err := NewProvider(&listingConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	lister, ok := provider.(ModelLister)
+	if !ok {
+		return nil, fmt.Errorf("%s does not offer a model list; check your provider's console for model names", cfg.Provider)
+	}
+
+	models, err := lister.ListModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(models, func(i, j int) bool {
+		return strings.ToLower(models[i].ID) < strings.ToLower(models[j].ID)
+	})
+	return models, nil
+}
+
+func FindModel(models []ModelInfo, id string) (ModelInfo, bool) {
+	for _, model := range models {
+		if strings.EqualFold(model.ID, id) {
+			return model, true
+		}
+	}
+	return ModelInfo{}, false
+}
+
+func FilterModels(models []ModelInfo, query string) []ModelInfo {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return models
+	}
+
+	var matches []ModelInfo
+	for _, model := range models {
+		if strings.Contains(strings.ToLower(model.ID), query) || strings.Contains(strings.ToLower(model.Name), query) {
+			matches = append(matches, model)
+		}
+	}
+	return matches
+}
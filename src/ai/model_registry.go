@@ -0,0 +1,102 @@
+package ai
+
+import (
+	"gct/src/config"
+	"strings"
+)
+
+type ModelSpec struct {
+	ContextWindow   int
+	MaxOutputTokens int
+	Pricing         config.ModelPricing
+}
+
+type registeredModel struct {
+	prefix string
+	spec   ModelSpec
+}
+
+var modelRegistry = []registeredModel{
+	{"gpt-4.1-nano", ModelSpec{1_047_576, 32_768, config.ModelPricing{Input: 0.10, Output: 0.40}}},
+	{"gpt-4.1-mini", ModelSpec{1_047_576, 32_768, config.ModelPricing{Input: 0.40, Output: 1.60}}},
+	{"gpt-4.1", ModelSpec{1_047_576, 32_768, config.ModelPricing{Input: 2.00, Output: 8.00}}},
+	{"gpt-4o-mini", ModelSpec{128_000, 16_384, config.ModelPricing{Input: 0.15, Output: 0.60}}},
+	{"gpt-4o", ModelSpec{128_000, 16_384, config.ModelPricing{Input: 2.50, Output: 10.00}}},
+	{"chatgpt-4o", ModelSpec{128_000, 16_384, config.ModelPricing{Input: 5.00, Output: 15.00}}},
+	{"gpt-4-turbo", ModelSpec{128_000, 4_096, config.ModelPricing{Input: 10.00, Output: 30.00}}},
+	{"gpt-4-32k", ModelSpec{32_768, 4_096, config.ModelPricing{Input: 60.00, Output: 120.00}}},
+	{"gpt-4", ModelSpec{8_192, 4_096, config.ModelPricing{Input: 30.00, Output: 60.00}}},
+	{"gpt-3.5-turbo", ModelSpec{16_385, 4_096, config.ModelPricing{Input: 0.50, Output: 1.50}}},
+	{"gpt-5-nano", ModelSpec{400_000, 128_000, config.ModelPricing{Input: 0.05, Output: 0.40}}},
+	{"gpt-5-mini", ModelSpec{400_000, 128_000, config.ModelPricing{Input: 0.25, Output: 2.00}}},
+	{"gpt-5", ModelSpec{400_000, 128_000, config.ModelPricing{Input: 1.25, Output: 10.00}}},
+	{"gpt-oss", ModelSpec{131_072, 32_768, config.ModelPricing{}}},
+	{"o1-mini", ModelSpec{128_000, 65_536, config.ModelPricing{Input: 1.10, Output: 4.40}}},
+	{"o1", ModelSpec{200_000, 100_000, config.ModelPricing{Input: 15.00, Output: 60.00}}},
+	{"o3-mini", ModelSpec{200_000, 100_000, config.ModelPricing{Input: 1.10, Output: 4.40}}},
+	{"o3", ModelSpec{200_000, 100_000, config.ModelPricing{Input: 2.00, Output: 8.00}}},
+	{"o4-mini", ModelSpec{200_000, 100_000, config.ModelPricing{Input: 1.10, Output: 4.40}}},
+	{"claude-opus-4", ModelSpec{200_000, 32_000, config.ModelPricing{Input: 15.00, Output: 75.00}}},
+	{"claude-sonnet-4", ModelSpec{200_000, 64_000, config.ModelPricing{Input: 3.00, Output: 15.00}}},
+	{"claude-3-7-sonnet", ModelSpec{200_000, 64_000, config.ModelPricing{Input: 3.00, Output: 15.00}}},
+	{"claude-3-5-sonnet", ModelSpec{200_000, 8_192, config.ModelPricing{Input: 3.00, Output: 15.00}}},
+	{"claude-3-5-haiku", ModelSpec{200_000, 8_192, config.ModelPricing{Input: 0.80, Output: 4.00}}},
+	{"claude-3-haiku", ModelSpec{200_000, 4_096, config.ModelPricing{Input: 0.25, Output: 1.25}}},
+	{"claude", ModelSpec{200_000, 4_096, config.ModelPricing{}}},
+	{"gemini-2.5-pro", ModelSpec{1_048_576, 65_536, config.ModelPricing{Input: 1.25, Output: 10.00}}},
+	{"gemini-2.5-flash-lite", ModelSpec{1_048_576, 65_536, config.ModelPricing{Input: 0.10, Output: 0.40}}},
+	{"gemini-2.5-flash", ModelSpec{1_048_576, 65_536, config.ModelPricing{Input: 0.30, Output: 2.50}}},
+	{"gemini-2.0-flash-lite", ModelSpec{1_048_576, 8_192, config.ModelPricing{Input: 0.075, Output: 0.30}}},
+	{"gemini-2.0-flash", ModelSpec{1_048_576, 8_192, config.ModelPricing{Input: 0.10, Output: 0.40}}},
+	{"gemini-1.5-pro", ModelSpec{2_097_152, 8_192, config.ModelPricing{Input: 1.25, Output: 5.00}}},
+	{"gemini-1.5-flash", ModelSpec{1_048_576, 8_192, config.ModelPricing{Input: 0.075, Output: 0.30}}},
+	{"gemini", ModelSpec{1_048_576, 8_192, config.ModelPricing{}}},
+	{"gemma-3", ModelSpec{131_072, 8_192, config.ModelPricing{}}},
+	{"deepseek-reasoner", ModelSpec{65_536, 32_768, config.ModelPricing{Input: 0.55, Output: 2.19}}},
+	{"deepseek-chat", ModelSpec{65_536, 8_192, config.ModelPricing{Input: 0.27, Output: 1.10}}},
+	{"deepseek", ModelSpec{65_536, 8_192, config.ModelPricing{}}},
+	{"grok-4", ModelSpec{256_000, 0, config.ModelPricing{Input: 3.00, Output: 15.00}}},
+	{"grok-3-mini", ModelSpec{131_072, 0, config.ModelPricing{Input: 0.30, Output: 0.50}}},
+	{"grok-3", ModelSpec{131_072, 0, config.ModelPricing{Input: 3.00, Output: 15.00}}},
+	{"mistral-large", ModelSpec{131_072, 0, config.ModelPricing{Input: 2.00, Output: 6.00}}},
+	{"codestral", ModelSpec{256_000, 0, config.ModelPricing{Input: 0.30, Output: 0.90}}},
+	{"llama-3.1", ModelSpec{131_072, 0, config.ModelPricing{}}},
+	{"llama-3.3", ModelSpec{131_072, 0, config.ModelPricing{}}},
+	{"llama-4", ModelSpec{131_072, 0, config.ModelPricing{}}},
+	{"qwen", ModelSpec{131_072, 0, config.ModelPricing{}}},
+	{"sonar-pro", ModelSpec{200_000, 8_000, config.ModelPricing{Input: 3.00, Output: 15.00}}},
+	{"sonar", ModelSpec{128_000, 0, config.ModelPricing{Input: 1.00, Output: 1.00}}},
+}
+
+func LookupModel(cfg *config.Config, provider config.ProviderConfig) ModelSpec {
+	spec := builtinModelSpec(provider.Model)
+
+	if strings.EqualFold(provider.Provider, "ollama") {
+		spec.ContextWindow = provider.OllamaContextSize
+	}
+	if override, ok := cfg.Models[provider.Model]; ok {
+		if override.ContextWindow > 0 {
+			spec.ContextWindow = override.ContextWindow
+		}
+		if override.MaxOutputTokens > 0 {
+			spec.MaxOutputTokens = override.MaxOutputTokens
+		}
+	}
+	if pricing, ok := cfg.Pricing[provider.Model]; ok {
+		spec.Pricing = pricing
+	}
+	if provider.ContextWindow > 0 {
+		spec.ContextWindow = provider.ContextWindow
+	}
+	return spec
+}
+
+func builtinModelSpec(model string) ModelSpec {
+	name := normalizeModelName(model)
+	for _, entry := range modelRegistry {
+		if strings.HasPrefix(name, entry.prefix) || strings.Contains(name, "."+entry.prefix) {
+			return entry.spec
+		}
+	}
+	return ModelSpec{}
+}
@@ -0,0 +1,238 @@
+package ai
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const ollamaDefaultBaseURL = "http://localhost:11434"
+
+type OllamaProvider struct {
+	client      *http.Client
+	model       string
+	baseURL     string
+	keepAlive   string
+	contextSize int
+}
+
+type ollamaRequest struct {
+	Model     string          `json:"model"`
+	Messages  []ollamaMessage `json:"messages"`
+	Stream    bool            `json:"stream"`
+	KeepAlive interface{}     `json:"keep_alive,omitempty"`
+	Format    interface{}     `json:"format,omitempty"`
+	Think     interface{}     `json:"think,omitempty"`
+	Options   *ollamaOptions  `json:"options,omitempty"`
+}
+
+type ollamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type ollamaOptions struct {
+	NumCtx      int      `json:"num_ctx,omitempty"`
+	NumPredict  int      `json:"num_predict,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+}
+
+type ollamaResponse struct {
+	Message struct {
+		Content  string `json:"content"`
+		Thinking string `json:"thinking"`
+	} `json:"message"`
+	Done            bool   `json:"done"`
+	PromptEvalCount int    `json:"prompt_eval_count"`
+	EvalCount       int    `json:"eval_count"`
+	Error           string `json:"error,omitempty"`
+}
+
+type ollamaTagsResponse struct {
+	Models []struct {
+		Name string `json:"name"`
+	} `json:"models"`
+}
+
+func NewOllamaProvider(modelName, baseURL, keepAlive string, contextSize int, client *http.Client) (*OllamaProvider, error) {
+	if modelName == "" {
+		return nil, fmt.Errorf("model name is required for Ollama (run 'ollama list' to see installed models)")
+	}
+	if baseURL == "" {
+		baseURL = ollamaDefaultBaseURL
+	}
+
+	return &OllamaProvider{
+		client:      httpClientOrDefault(client, localRequestTimeout),
+		model:       modelName,
+		baseURL:     strings.TrimSuffix(baseURL, "/"),
+		keepAlive:   keepAlive,
+		contextSize: contextSize,
+	}, nil
+}
+
+func (p *OllamaProvider) SetBaseURL(baseURL string) {
+	p.baseURL = strings.TrimSuffix(baseURL, "/")
+}
+
+func (p *OllamaProvider) apiBaseURL() string {
+	return p.baseURL
+}
+
+func (p *OllamaProvider) newRequest(req Request, stream bool) (ollamaRequest, http.Header) {
+	apiMessages := make([]ollamaMessage, 0, len(req.Messages))
+	for _, m := range req.Messages {
+		apiMessages = append(apiMessages, ollamaMessage{Role: string(m.Role), Content: m.Content})
+	}
+
+	payload := ollamaRequest{
+		Model:    p.model,
+		Messages: apiMessages,
+		Stream:   stream,
+	}
+	if req.Options.Schema != nil {
+		payload.Format = req.Options.Schema.Schema
+	}
+	switch effort := req.Options.ReasoningEffort; {
+	case effort == "minimal":
+		payload.Think = "low"
+	case effort != "":
+		payload.Think = effort
+	case req.Options.ThinkingBudget > 0:
+		payload.Think = true
+	}
+	if p.keepAlive != "" {
+		if seconds, err := strconv.Atoi(p.keepAlive); err == nil {
+			payload.KeepAlive = seconds
+		} else {
+			payload.KeepAlive = p.keepAlive
+		}
+	}
+	options := ollamaOptions{
+		NumCtx:      p.contextSize,
+		NumPredict:  req.Options.MaxTokens,
+		Temperature: req.Options.Temperature,
+		TopP:        req.Options.TopP,
+		Stop:        req.Options.StopSequences,
+	}
+	if options.NumCtx > 0 || options.NumPredict > 0 || options.Temperature != nil || options.TopP != nil || len(options.Stop) > 0 {
+		payload.Options = &options
+	}
+
+	headers := http.Header{}
+	headers.Set("Content-Type", "application/json")
+
+	return payload, headers
+}
+
+func (p *OllamaProvider) errorFromResponse(statusCode int, respBody []byte) error {
+	var apiResp ollamaResponse
+	if err := json.Unmarshal(respBody, &apiResp); err == nil && apiResp.Error != "" {
+		return newAPIError("Ollama", p.model, statusCode, respBody, fmt.Sprintf("ollama api error: %s", apiResp.Error))
+	}
+	return newAPIError("Ollama", p.model, statusCode, respBody, fmt.Sprintf("received non-200 status from ollama: %s", string(respBody)))
+}
+
+func (p *OllamaProvider) Generate(ctx context.Context, req Request) (*Response, error) {
+	payload, headers := p.newRequest(req, false)
+
+	respBody, statusCode, err := doAPIRequest(ctx, p.client, "POST", p.baseURL+"/api/chat", headers, payload)
+	if err != nil {
+		return nil, requestError("Ollama", fmt.Errorf("failed to send request to ollama at %s: %w", p.baseURL, err))
+	}
+
+	if statusCode != http.StatusOK {
+		return nil, p.errorFromResponse(statusCode, respBody)
+	}
+
+	var apiResp ollamaResponse
+	if err := json.Unmarshal(respBody, &apiResp); err != nil {
+		return nil, fmt.Errorf("failed to parse ollama json response: %w", err)
+	}
+
+	result := newReasoningResponse(apiResp.Message.Content, apiResp.Message.Thinking,
+		Usage{InputTokens: apiResp.PromptEvalCount, OutputTokens: apiResp.EvalCount}, thinkTagsFor(p.model, true))
+	if result.Text == "" {
+		return nil, fmt.Errorf("received an empty or invalid response from ollama")
+	}
+	return result, nil
+}
+
+func (p *OllamaProvider) GenerateStream(ctx context.Context, req Request, onChunk StreamHandler) (*Response, error) {
+	payload, headers := p.newRequest(req, true)
+
+	resp, err := doStreamRequest(ctx, p.client, "POST", p.baseURL+"/api/chat", headers, payload)
+	if err != nil {
+		return nil, requestError("Ollama", fmt.Errorf("failed to send request to ollama at %s: %w", p.baseURL, err))
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, p.errorFromResponse(resp.StatusCode, respBody)
+	}
+
+	stream := newReasoningStream(ctx, onChunk, thinkTagsFor(p.model, true))
+	var usage Usage
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxSSELineSize)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var chunk ollamaResponse
+		if err := json.Unmarshal([]byte(line), &chunk); err != nil {
+			return nil, fmt.Errorf("failed to parse ollama stream chunk: %w", err)
+		}
+		if chunk.Error != "" {
+			return nil, newAPIError("Ollama", p.model, 0, nil, fmt.Sprintf("ollama stream failed: %s", chunk.Error))
+		}
+		if chunk.Done {
+			usage = Usage{InputTokens: chunk.PromptEvalCount, OutputTokens: chunk.EvalCount}
+		}
+		stream.Reasoning(chunk.Message.Thinking)
+		stream.Text(chunk.Message.Content)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, requestError("Ollama", fmt.Errorf("ollama stream failed: %w", err))
+	}
+
+	result := stream.Response(usage)
+	if result.Text == "" {
+		return nil, fmt.Errorf("received an empty or invalid response from ollama")
+	}
+	return result, nil
+}
+
+func (p *OllamaProvider) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	respBody, statusCode, err := doAPIRequest(ctx, p.client, "GET", p.baseURL+"/api/tags", nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach ollama at %s: %w", p.baseURL, err)
+	}
+	if statusCode != http.StatusOK {
+		return nil, p.errorFromResponse(statusCode, respBody)
+	}
+
+	var tags ollamaTagsResponse
+	if err := json.Unmarshal(respBody, &tags); err != nil {
+		return nil, fmt.Errorf("failed to parse ollama model list: %w", err)
+	}
+
+	models := make([]ModelInfo, 0, len(tags.Models))
+	for _, m := range tags.Models {
+		models = append(models, ModelInfo{ID: m.Name})
+	}
+	return models, nil
+}
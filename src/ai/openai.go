@@ -4,8 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
-	"time"
+	"strings"
 )
 
 const (
@@ -20,8 +21,17 @@ type OpenAIProvider struct {
 }
 
 type openAIRequest struct {
-	Model    string          `json:"model"`
-	Messages []openAIMessage `json:"messages"`
+	Model          string                `json:"model"`
+	Messages       []openAIMessage       `json:"messages"`
+	Stream         bool                  `json:"stream,omitempty"`
+	StreamOptions  *openAIStreamOptions  `json:"stream_options,omitempty"`
+	MaxTokens      int                   `json:"max_completion_tokens,omitempty"`
+	Temperature    *float64              `json:"temperature,omitempty"`
+	TopP           *float64              `json:"top_p,omitempty"`
+	Stop           []string              `json:"stop,omitempty"`
+	ResponseFormat *openAIResponseFormat `json:"response_format,omitempty"`
+
+	ReasoningEffort string `json:"reasoning_effort,omitempty"`
 }
 
 type openAIMessage struct {
@@ -32,62 +42,158 @@ type openAIMessage struct {
 type openAIResponse struct {
 	Choices []struct {
 		Message struct {
-			Content string `json:"content"`
+			Content          string `json:"content"`
+			ReasoningContent string `json:"reasoning_content"`
+			Reasoning        string `json:"reasoning"`
 		} `json:"message"`
 	} `json:"choices"`
+	Usage *openAIUsage `json:"usage,omitempty"`
 	Error *struct {
 		Message string `json:"message"`
 		Type    string `json:"type"`
 	} `json:"error,omitempty"`
 }
 
-func NewOpenAIProvider(apiKey, modelName string) (*OpenAIProvider, error) {
+func NewOpenAIProvider(apiKey, modelName string, client *http.Client) (*OpenAIProvider, error) {
 	if apiKey == "" {
-		return nil, fmt.Errorf("OpenAI API key is required")
+		return nil, missingCredentials("OpenAI", "OpenAI API key is required")
 	}
 
 	return &OpenAIProvider{
-		client: &http.Client{
-			Timeout: 90 * time.Second,
-		},
+		client:  httpClientOrDefault(client, defaultRequestTimeout),
 		apiKey:  apiKey,
 		model:   modelName,
 		baseURL: openAIBaseURL,
 	}, nil
 }
 
-func (p *OpenAIProvider) Generate(ctx context.Context, prompt string) (string, error) {
+func (p *OpenAIProvider) SetBaseURL(baseURL string) {
+	p.baseURL = strings.TrimSuffix(baseURL, "/")
+}
+
+func (p *OpenAIProvider) apiBaseURL() string {
+	return p.baseURL
+}
+
+func (p *OpenAIProvider) newRequest(req Request, stream bool) (openAIRequest, http.Header) {
+	apiMessages := make([]openAIMessage, 0, len(req.Messages))
+	for _, m := range req.Messages {
+		apiMessages = append(apiMessages, openAIMessage{Role: string(m.Role), Content: m.Content})
+	}
+
 	payload := openAIRequest{
-		Model: p.model,
-		Messages: []openAIMessage{
-			{Role: "user", Content: prompt},
-		},
+		Model:          p.model,
+		Messages:       apiMessages,
+		Stream:         stream,
+		MaxTokens:      req.Options.MaxTokens,
+		Temperature:    req.Options.Temperature,
+		TopP:           req.Options.TopP,
+		Stop:           req.Options.StopSequences,
+		ResponseFormat: newOpenAIResponseFormat(req.Options.Schema, jsonModeSchema),
+
+		ReasoningEffort: req.Options.ReasoningEffort,
+	}
+	if stream {
+		payload.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
 	}
 
 	headers := http.Header{}
 	headers.Set("Content-Type", "application/json")
 	headers.Set("Authorization", "Bearer "+p.apiKey)
 
+	return payload, headers
+}
+
+func (p *OpenAIProvider) errorFromResponse(statusCode int, respBody []byte) error {
+	var apiResp openAIResponse
+	if err := json.Unmarshal(respBody, &apiResp); err == nil && apiResp.Error != nil {
+		return newAPIError("OpenAI", p.model, statusCode, respBody, fmt.Sprintf("openai api error (type: %s): %s", apiResp.Error.Type, apiResp.Error.Message))
+	}
+	return newAPIError("OpenAI", p.model, statusCode, respBody, fmt.Sprintf("received non-200 status from openai: %d", statusCode))
+}
+
+func (p *OpenAIProvider) Generate(ctx context.Context, req Request) (*Response, error) {
+	payload, headers := p.newRequest(req, false)
+
 	respBody, statusCode, err := doAPIRequest(ctx, p.client, "POST", p.baseURL+"/chat/completions", headers, payload)
 	if err != nil {
-		return "", fmt.Errorf("failed to send request to openai: %w", err)
+		return nil, requestError("OpenAI", fmt.Errorf("failed to send request to openai: %w", err))
+	}
+
+	if statusCode != http.StatusOK {
+		return nil, p.errorFromResponse(statusCode, respBody)
 	}
 
 	var apiResp openAIResponse
 	if err := json.Unmarshal(respBody, &apiResp); err != nil {
-		return "", fmt.Errorf("failed to parse openai json response: %w", err)
+		return nil, fmt.Errorf("failed to parse openai json response: %w", err)
+	}
+
+	if len(apiResp.Choices) == 0 {
+		return nil, fmt.Errorf("received an empty or invalid response from openai")
+	}
+
+	message := apiResp.Choices[0].Message
+	result := newReasoningResponse(message.Content, message.ReasoningContent+message.Reasoning, apiResp.Usage.toUsage(), thinkTagsFor(p.model, false))
+	if result.Text == "" {
+		return nil, fmt.Errorf("received an empty or invalid response from openai")
+	}
+	return result, nil
+}
+
+func (p *OpenAIProvider) GenerateStream(ctx context.Context, req Request, onChunk StreamHandler) (*Response, error) {
+	payload, headers := p.newRequest(req, true)
+
+	resp, err := doStreamRequest(ctx, p.client, "POST", p.baseURL+"/chat/completions", headers, payload)
+	if err != nil {
+		return nil, requestError("OpenAI", fmt.Errorf("failed to send request to openai: %w", err))
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, p.errorFromResponse(resp.StatusCode, respBody)
+	}
+
+	result, err := readOpenAIStream(ctx, "OpenAI", resp.Body, onChunk, thinkTagsFor(p.model, false))
+	if err != nil {
+		return nil, requestError("OpenAI", fmt.Errorf("openai stream failed: %w", err))
 	}
+	if result.Text == "" {
+		return nil, fmt.Errorf("received an empty or invalid response from openai")
+	}
+
+	return result, nil
+}
 
+type openAIModelsResponse struct {
+	Data []struct {
+		ID string `json:"id"`
+	} `json:"data"`
+}
+
+func (p *OpenAIProvider) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	headers := http.Header{}
+	headers.Set("Authorization", "Bearer "+p.apiKey)
+
+	respBody, statusCode, err := doAPIRequest(ctx, p.client, "GET", p.baseURL+"/models", headers, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list openai models: %w", err)
+	}
 	if statusCode != http.StatusOK {
-		if apiResp.Error != nil {
-			return "", fmt.Errorf("openai api error (type: %s): %s", apiResp.Error.Type, apiResp.Error.Message)
-		}
-		return "", fmt.Errorf("received non-200 status from openai: %d", statusCode)
+		return nil, p.errorFromResponse(statusCode, respBody)
 	}
 
-	if len(apiResp.Choices) == 0 || apiResp.Choices[0].Message.Content == "" {
-		return "", fmt.Errorf("received an empty or invalid response from openai")
+	var list openAIModelsResponse
+	if err := json.Unmarshal(respBody, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse openai model list: %w", err)
 	}
 
-	return apiResp.Choices[0].Message.Content, nil
+	models := make([]ModelInfo, 0, len(list.Data))
+	for _, m := range list.Data {
+		models = append(models, ModelInfo{ID: m.ID})
+	}
+	return models, nil
 }
@@ -4,20 +4,35 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
-	"time"
+	"strings"
+	"sync/atomic"
 )
 
 type OpenAICompatibleProvider struct {
-	client  *http.Client
-	apiKey  string
-	model   string
-	baseURL string
+	client   *http.Client
+	name     string
+	apiKey   string
+	model    string
+	baseURL  string
+	jsonMode jsonMode
+
+	noStreamUsage atomic.Bool
 }
 
 type openAICompatRequest struct {
-	Model    string                `json:"model"`
-	Messages []openAICompatMessage `json:"messages"`
+	Model          string                `json:"model"`
+	Messages       []openAICompatMessage `json:"messages"`
+	Stream         bool                  `json:"stream,omitempty"`
+	MaxTokens      int                   `json:"max_tokens,omitempty"`
+	Temperature    *float64              `json:"temperature,omitempty"`
+	TopP           *float64              `json:"top_p,omitempty"`
+	Stop           []string              `json:"stop,omitempty"`
+	ResponseFormat *openAIResponseFormat `json:"response_format,omitempty"`
+	StreamOptions  *openAIStreamOptions  `json:"stream_options,omitempty"`
+
+	ReasoningEffort string `json:"reasoning_effort,omitempty"`
 }
 
 type openAICompatMessage struct {
@@ -28,65 +43,185 @@ type openAICompatMessage struct {
 type openAICompatResponse struct {
 	Choices []struct {
 		Message struct {
-			Content string `json:"content"`
+			Content          string `json:"content"`
+			ReasoningContent string `json:"reasoning_content"`
+			Reasoning        string `json:"reasoning"`
 		} `json:"message"`
 	} `json:"choices"`
+	Usage *openAIUsage `json:"usage,omitempty"`
 	Error *struct {
 		Message string `json:"message"`
 		Type    string `json:"type"`
 	} `json:"error,omitempty"`
 }
 
-func NewOpenAICompatibleProvider(apiKey, modelName, baseURL string) (*OpenAICompatibleProvider, error) {
+type openAICompatModelsResponse struct {
+	Data []struct {
+		ID               string `json:"id"`
+		ContextWindow    int    `json:"context_window"`
+		ContextLength    int    `json:"context_length"`
+		MaxContextLength int    `json:"max_context_length"`
+	} `json:"data"`
+}
+
+func NewOpenAICompatibleProvider(apiKey, modelName, baseURL string, client *http.Client) (*OpenAICompatibleProvider, error) {
 	if apiKey == "" {
-		return nil, fmt.Errorf("API key is required for OpenAI Compatible provider")
+		return nil, missingCredentials("OpenAI Compatible", "API key is required for OpenAI Compatible provider")
 	}
 	if baseURL == "" {
 		return nil, fmt.Errorf("endpoint URL is required for OpenAI Compatible provider")
 	}
 
 	return &OpenAICompatibleProvider{
-		client: &http.Client{
-			Timeout: 90 * time.Second,
-		},
+		client:  httpClientOrDefault(client, defaultRequestTimeout),
+		name:    "OpenAI Compatible",
 		apiKey:  apiKey,
 		model:   modelName,
 		baseURL: baseURL,
 	}, nil
 }
 
-func (p *OpenAICompatibleProvider) Generate(ctx context.Context, prompt string) (string, error) {
+func (p *OpenAICompatibleProvider) SetBaseURL(baseURL string) {
+	p.baseURL = strings.TrimSuffix(baseURL, "/")
+}
+
+func (p *OpenAICompatibleProvider) apiBaseURL() string {
+	return p.baseURL
+}
+
+func (p *OpenAICompatibleProvider) newRequest(req Request, stream bool) (openAICompatRequest, http.Header) {
+	apiMessages := make([]openAICompatMessage, 0, len(req.Messages))
+	for _, m := range req.Messages {
+		apiMessages = append(apiMessages, openAICompatMessage{Role: string(m.Role), Content: m.Content})
+	}
+
 	payload := openAICompatRequest{
-		Model: p.model,
-		Messages: []openAICompatMessage{
-			{Role: "user", Content: prompt},
-		},
+		Model:          p.model,
+		Messages:       apiMessages,
+		Stream:         stream,
+		MaxTokens:      req.Options.MaxTokens,
+		Temperature:    req.Options.Temperature,
+		TopP:           req.Options.TopP,
+		Stop:           req.Options.StopSequences,
+		ResponseFormat: newOpenAIResponseFormat(req.Options.Schema, p.jsonMode),
+
+		ReasoningEffort: req.Options.ReasoningEffort,
+	}
+	if stream && !p.noStreamUsage.Load() {
+		payload.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
 	}
 
 	headers := http.Header{}
 	headers.Set("Content-Type", "application/json")
-	headers.Set("Authorization", "Bearer "+p.apiKey)
+	if p.apiKey != "" {
+		headers.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	return payload, headers
+}
+
+func (p *OpenAICompatibleProvider) errorFromResponse(statusCode int, respBody []byte) error {
+	var apiResp openAICompatResponse
+	if err := json.Unmarshal(respBody, &apiResp); err == nil && apiResp.Error != nil {
+		return newAPIError(p.name, p.model, statusCode, respBody, fmt.Sprintf("api error (type: %s): %s", apiResp.Error.Type, apiResp.Error.Message))
+	}
+	return newAPIError(p.name, p.model, statusCode, respBody, fmt.Sprintf("received non-200 status from endpoint: %d", statusCode))
+}
+
+func (p *OpenAICompatibleProvider) Generate(ctx context.Context, req Request) (*Response, error) {
+	payload, headers := p.newRequest(req, false)
 
 	respBody, statusCode, err := doAPIRequest(ctx, p.client, "POST", p.baseURL+"/chat/completions", headers, payload)
 	if err != nil {
-		return "", fmt.Errorf("failed to send request to compatible endpoint: %w", err)
+		return nil, requestError(p.name, fmt.Errorf("failed to send request to compatible endpoint: %w", err))
+	}
+
+	if statusCode != http.StatusOK {
+		return nil, p.errorFromResponse(statusCode, respBody)
 	}
 
 	var apiResp openAICompatResponse
 	if err := json.Unmarshal(respBody, &apiResp); err != nil {
-		return "", fmt.Errorf("failed to parse json response: %w", err)
+		return nil, fmt.Errorf("failed to parse json response: %w", err)
 	}
 
-	if statusCode != http.StatusOK {
-		if apiResp.Error != nil {
-			return "", fmt.Errorf("api error (type: %s): %s", apiResp.Error.Type, apiResp.Error.Message)
+	if len(apiResp.Choices) == 0 {
+		return nil, fmt.Errorf("received an empty or invalid response from the endpoint")
+	}
+
+	message := apiResp.Choices[0].Message
+	result := newReasoningResponse(message.Content, message.ReasoningContent+message.Reasoning, apiResp.Usage.toUsage(), thinkTagsFor(p.model, true))
+	if result.Text == "" {
+		return nil, fmt.Errorf("received an empty or invalid response from the endpoint")
+	}
+	return result, nil
+}
+
+func (p *OpenAICompatibleProvider) GenerateStream(ctx context.Context, req Request, onChunk StreamHandler) (*Response, error) {
+	payload, headers := p.newRequest(req, true)
+
+	resp, err := doStreamRequest(ctx, p.client, "POST", p.baseURL+"/chat/completions", headers, payload)
+	if err != nil {
+		return nil, requestError(p.name, fmt.Errorf("failed to send request to compatible endpoint: %w", err))
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		if payload.StreamOptions != nil && rejectsStreamOptions(resp.StatusCode, respBody) {
+			p.noStreamUsage.Store(true)
+			return p.GenerateStream(ctx, req, onChunk)
 		}
-		return "", fmt.Errorf("received non-200 status from endpoint: %d", statusCode)
+		return nil, p.errorFromResponse(resp.StatusCode, respBody)
+	}
+
+	result, err := readOpenAIStream(ctx, p.name, resp.Body, onChunk, thinkTagsFor(p.model, true))
+	if err != nil {
+		return nil, requestError(p.name, fmt.Errorf("compatible endpoint stream failed: %w", err))
+	}
+	if result.Text == "" {
+		return nil, fmt.Errorf("received an empty or invalid response from the endpoint")
+	}
+
+	return result, nil
+}
+
+func rejectsStreamOptions(statusCode int, respBody []byte) bool {
+	return (statusCode == http.StatusBadRequest || statusCode == http.StatusUnprocessableEntity) &&
+		strings.Contains(string(respBody), "stream_options")
+}
+
+func (p *OpenAICompatibleProvider) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	headers := http.Header{}
+	if p.apiKey != "" {
+		headers.Set("Authorization", "Bearer "+p.apiKey)
 	}
 
-	if len(apiResp.Choices) == 0 || apiResp.Choices[0].Message.Content == "" {
-		return "", fmt.Errorf("received an empty or invalid response from the endpoint")
+	respBody, statusCode, err := doAPIRequest(ctx, p.client, "GET", p.baseURL+"/models", headers, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach endpoint at %s: %w", p.baseURL, err)
+	}
+	if statusCode != http.StatusOK {
+		return nil, p.errorFromResponse(statusCode, respBody)
+	}
+
+	var list openAICompatModelsResponse
+	if err := json.Unmarshal(respBody, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse model list: %w", err)
 	}
 
-	return apiResp.Choices[0].Message.Content, nil
+	models := make([]ModelInfo, 0, len(list.Data))
+	for _, m := range list.Data {
+		contextWindow := m.ContextWindow
+		if contextWindow == 0 {
+			contextWindow = m.ContextLength
+		}
+		if contextWindow == 0 {
+			contextWindow = m.MaxContextLength
+		}
+		models = append(models, ModelInfo{ID: m.ID, ContextWindow: contextWindow})
+	}
+	return models, nil
 }
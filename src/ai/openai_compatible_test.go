@@ -0,0 +1,48 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenAICompatibleStreamRequestsUsage(t *testing.T) {
+	for _, supported := range []bool{true, false} {
+		var requests int
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			var payload map[string]interface{}
+			_ = json.NewDecoder(r.Body).Decode(&payload)
+			if _, ok := payload["stream_options"]; ok && !supported {
+				http.Error(w, `{"error":{"message":"Unrecognized request argument supplied: stream_options"}}`, http.StatusBadRequest)
+				return
+			}
+			fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"feat: add x\"}}]}\n\n")
+			if supported {
+				fmt.Fprint(w, "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":12,\"completion_tokens\":3}}\n\n")
+			}
+			fmt.Fprint(w, "data: [DONE]\n\n")
+		}))
+
+		provider, err := NewOpenAICompatibleProvider("key", "local", server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 2; i++ {
+			resp, err := provider.GenerateStream(context.Background(), Request{Messages: UserPrompt("hi")}, nil)
+			if err != nil || resp.Text != "feat: add x" {
+				t.Fatalf("supported=%v: %v, %+v", supported, err, resp)
+			}
+			if supported && resp.Usage.InputTokens != 12 {
+				t.Fatalf("usage was not read from the stream: %+v", resp.Usage)
+			}
+		}
+		if want := map[bool]int{true: 2, false: 3}[supported]; requests != want {
+			t.Fatalf("supported=%v: %d requests, want %d", supported, requests, want)
+		}
+		server.Close()
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
-	"time"
+	"strconv"
+	"strings"
 )
 
 const (
@@ -21,8 +23,21 @@ type OpenRouterProvider struct {
 }
 
 type openRouterRequest struct {
-	Model    string              `json:"model"`
-	Messages []openRouterMessage `json:"messages"`
+	Model          string                `json:"model"`
+	Messages       []openRouterMessage   `json:"messages"`
+	Stream         bool                  `json:"stream,omitempty"`
+	StreamOptions  *openAIStreamOptions  `json:"stream_options,omitempty"`
+	MaxTokens      int                   `json:"max_tokens,omitempty"`
+	Temperature    *float64              `json:"temperature,omitempty"`
+	TopP           *float64              `json:"top_p,omitempty"`
+	Stop           []string              `json:"stop,omitempty"`
+	ResponseFormat *openAIResponseFormat `json:"response_format,omitempty"`
+	Reasoning      *openRouterReasoning  `json:"reasoning,omitempty"`
+}
+
+type openRouterReasoning struct {
+	Effort    string `json:"effort,omitempty"`
+	MaxTokens int    `json:"max_tokens,omitempty"`
 }
 
 type openRouterMessage struct {
@@ -33,35 +48,60 @@ type openRouterMessage struct {
 type openRouterResponse struct {
 	Choices []struct {
 		Message struct {
-			Content string `json:"content"`
+			Content   string `json:"content"`
+			Reasoning string `json:"reasoning"`
 		} `json:"message"`
 	} `json:"choices"`
+	Usage *openAIUsage `json:"usage,omitempty"`
 	Error *struct {
 		Message string `json:"message"`
 	} `json:"error,omitempty"`
 }
 
-func NewOpenRouterProvider(apiKey, modelName string) (*OpenRouterProvider, error) {
+func NewOpenRouterProvider(apiKey, modelName string, client *http.Client) (*OpenRouterProvider, error) {
 	if apiKey == "" {
-		return nil, fmt.Errorf("OpenRouter API key is required")
+		return nil, missingCredentials("OpenRouter", "OpenRouter API key is required")
 	}
 
 	return &OpenRouterProvider{
-		client: &http.Client{
-			Timeout: 90 * time.Second,
-		},
+		client:  httpClientOrDefault(client, defaultRequestTimeout),
 		apiKey:  apiKey,
 		model:   modelName,
 		baseURL: openRouterAPIBaseURL,
 	}, nil
 }
 
-func (p *OpenRouterProvider) Generate(ctx context.Context, prompt string) (string, error) {
+func (p *OpenRouterProvider) SetBaseURL(baseURL string) {
+	p.baseURL = strings.TrimSuffix(baseURL, "/")
+}
+
+func (p *OpenRouterProvider) apiBaseURL() string {
+	return p.baseURL
+}
+
+func (p *OpenRouterProvider) newRequest(req Request, stream bool) (openRouterRequest, http.Header) {
+	apiMessages := make([]openRouterMessage, 0, len(req.Messages))
+	for _, m := range req.Messages {
+		apiMessages = append(apiMessages, openRouterMessage{Role: string(m.Role), Content: m.Content})
+	}
+
 	payload := openRouterRequest{
-		Model: p.model,
-		Messages: []openRouterMessage{
-			{Role: "user", Content: prompt},
-		},
+		Model:          p.model,
+		Messages:       apiMessages,
+		Stream:         stream,
+		MaxTokens:      req.Options.MaxTokens,
+		Temperature:    req.Options.Temperature,
+		TopP:           req.Options.TopP,
+		Stop:           req.Options.StopSequences,
+		ResponseFormat: newOpenAIResponseFormat(req.Options.Schema, jsonModeSchema),
+	}
+	if stream {
+		payload.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
+	}
+	if req.Options.ReasoningEffort != "" {
+		payload.Reasoning = &openRouterReasoning{Effort: req.Options.ReasoningEffort}
+	} else if req.Options.ThinkingBudget > 0 {
+		payload.Reasoning = &openRouterReasoning{MaxTokens: req.Options.ThinkingBudget}
 	}
 
 	headers := http.Header{}
@@ -70,26 +110,119 @@ func (p *OpenRouterProvider) Generate(ctx context.Context, prompt string) (strin
 	headers.Set("HTTP-Referer", gctAppName)
 	headers.Set("X-Title", "GCT AI Commit")
 
+	return payload, headers
+}
+
+func (p *OpenRouterProvider) errorFromResponse(statusCode int, respBody []byte) error {
+	var apiResp openRouterResponse
+	if err := json.Unmarshal(respBody, &apiResp); err == nil && apiResp.Error != nil {
+		return newAPIError("OpenRouter", p.model, statusCode, respBody, fmt.Sprintf("openrouter api error (%d): %s", statusCode, apiResp.Error.Message))
+	}
+	return newAPIError("OpenRouter", p.model, statusCode, respBody, fmt.Sprintf("received non-200 status from openrouter: %d", statusCode))
+}
+
+func (p *OpenRouterProvider) Generate(ctx context.Context, req Request) (*Response, error) {
+	payload, headers := p.newRequest(req, false)
+
 	respBody, statusCode, err := doAPIRequest(ctx, p.client, "POST", p.baseURL+"/chat/completions", headers, payload)
 	if err != nil {
-		return "", fmt.Errorf("failed to send request to openrouter: %w", err)
+		return nil, requestError("OpenRouter", fmt.Errorf("failed to send request to openrouter: %w", err))
+	}
+
+	if statusCode != http.StatusOK {
+		return nil, p.errorFromResponse(statusCode, respBody)
 	}
 
 	var apiResp openRouterResponse
 	if err := json.Unmarshal(respBody, &apiResp); err != nil {
-		return "", fmt.Errorf("failed to parse openrouter json response: %w", err)
+		return nil, fmt.Errorf("failed to parse openrouter json response: %w", err)
+	}
+
+	if len(apiResp.Choices) == 0 {
+		return nil, fmt.Errorf("received an empty or invalid response from openrouter")
+	}
+
+	message := apiResp.Choices[0].Message
+	result := newReasoningResponse(message.Content, message.Reasoning, apiResp.Usage.toUsage(), thinkTagsFor(p.model, true))
+	if result.Text == "" {
+		return nil, fmt.Errorf("received an empty or invalid response from openrouter")
 	}
+	return result, nil
+}
+
+func (p *OpenRouterProvider) GenerateStream(ctx context.Context, req Request, onChunk StreamHandler) (*Response, error) {
+	payload, headers := p.newRequest(req, true)
+
+	resp, err := doStreamRequest(ctx, p.client, "POST", p.baseURL+"/chat/completions", headers, payload)
+	if err != nil {
+		return nil, requestError("OpenRouter", fmt.Errorf("failed to send request to openrouter: %w", err))
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, p.errorFromResponse(resp.StatusCode, respBody)
+	}
+
+	result, err := readOpenAIStream(ctx, "OpenRouter", resp.Body, onChunk, thinkTagsFor(p.model, true))
+	if err != nil {
+		return nil, requestError("OpenRouter", fmt.Errorf("openrouter stream failed: %w", err))
+	}
+	if result.Text == "" {
+		return nil, fmt.Errorf("received an empty or invalid response from openrouter")
+	}
+
+	return result, nil
+}
+
+type openRouterModelsResponse struct {
+	Data []struct {
+		ID            string `json:"id"`
+		Name          string `json:"name"`
+		ContextLength int    `json:"context_length"`
+		Pricing       struct {
+			Prompt     string `json:"prompt"`
+			Completion string `json:"completion"`
+		} `json:"pricing"`
+	} `json:"data"`
+}
+
+func perMillionTokens(perToken string) float64 {
+	price, err := strconv.ParseFloat(perToken, 64)
+	if err != nil || price < 0 {
+		return 0
+	}
+	return price * 1_000_000
+}
 
+func (p *OpenRouterProvider) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	headers := http.Header{}
+	headers.Set("Authorization", "Bearer "+p.apiKey)
+
+	respBody, statusCode, err := doAPIRequest(ctx, p.client, "GET", p.baseURL+"/models", headers, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list openrouter models: %w", err)
+	}
 	if statusCode != http.StatusOK {
-		if apiResp.Error != nil {
-			return "", fmt.Errorf("openrouter api error (%d): %s", statusCode, apiResp.Error.Message)
-		}
-		return "", fmt.Errorf("received non-200 status from openrouter: %d", statusCode)
+		return nil, p.errorFromResponse(statusCode, respBody)
 	}
 
-	if len(apiResp.Choices) == 0 || apiResp.Choices[0].Message.Content == "" {
-		return "", fmt.Errorf("received an empty or invalid response from openrouter")
+	var list openRouterModelsResponse
+	if err := json.Unmarshal(respBody, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse openrouter model list: %w", err)
 	}
 
-	return apiResp.Choices[0].Message.Content, nil
+	models := make([]ModelInfo, 0, len(list.Data))
+	for _, m := range list.Data {
+		models = append(models, ModelInfo{
+			ID:            m.ID,
+			Name:          m.Name,
+			ContextWindow: m.ContextLength,
+			InputPrice:    perMillionTokens(m.Pricing.Prompt),
+			OutputPrice:   perMillionTokens(m.Pricing.Completion),
+		})
+	}
+	return models, nil
 }
@@ -1,7 +1,108 @@
 package ai
 
-import "context"
+import (
+	"context"
+	"fmt"
+	"gct/src/config"
+	"strings"
+)
+
+type Role string
+
+const (
+	RoleSystem    Role = "system"
+	RoleUser      Role = "user"
+	RoleAssistant Role = "assistant"
+)
+
+type Message struct {
+	Role    Role
+	Content string
+}
+
+type GenerationOptions struct {
+	MaxTokens     int
+	Temperature   *float64
+	TopP          *float64
+	StopSequences []string
+	Schema        *ResponseSchema
+
+	ReasoningEffort string
+	ThinkingBudget  int
+}
+
+type Request struct {
+	Messages []Message
+	Options  GenerationOptions
+}
+
+type Usage struct {
+	InputTokens      int
+	OutputTokens     int
+	CacheReadTokens  int
+	CacheWriteTokens int
+}
+
+type Response struct {
+	Text      string
+	Reasoning string
+	Usage     Usage
+}
+
+type StreamHandler func(chunk string)
 
 type AIProvider interface {
-	Generate(ctx context.Context, prompt string) (string, error)
+	Generate(ctx context.Context, req Request) (*Response, error)
+	GenerateStream(ctx context.Context, req Request, onChunk StreamHandler) (*Response, error)
+}
+
+func NewGenerationOptions(params config.GenerationParams) GenerationOptions {
+	return GenerationOptions{
+		MaxTokens:     params.MaxTokens,
+		Temperature:   params.Temperature,
+		TopP:          params.TopP,
+		StopSequences: params.Stop,
+
+		ReasoningEffort: params.ReasoningEffort,
+		ThinkingBudget:  params.ThinkingBudget,
+	}
+}
+
+func UserPrompt(prompt string) []Message {
+	return []Message{{Role: RoleUser, Content: prompt}}
+}
+
+func SystemPrompt(system, prompt string) []Message {
+	return []Message{{Role: RoleSystem, Content: system}, {Role: RoleUser, Content: prompt}}
+}
+
+func splitSystemMessages(messages []Message) (string, []Message) {
+	var system []string
+	var rest []Message
+	for _, msg := range messages {
+		if msg.Role == RoleSystem {
+			system = append(system, msg.Content)
+			continue
+		}
+		rest = append(rest, msg)
+	}
+	return strings.Join(system, "\n\n"), rest
+}
+
+func flattenMessages(messages []Message, userLabel, assistantLabel string) string {
+	system, rest := splitSystemMessages(messages)
+
+	var sb strings.Builder
+	if system != "" {
+		sb.WriteString(system)
+	}
+	for _, msg := range rest {
+		label := userLabel
+		if msg.Role == RoleAssistant {
+			label = assistantLabel
+		}
+		sb.WriteString(fmt.Sprintf("\n\n%s: %s", label, msg.Content))
+	}
+	sb.WriteString(fmt.Sprintf("\n\n%s:", assistantLabel))
+	return sb.String()
 }
@@ -0,0 +1,241 @@
+package ai
+
+import (
+	"context"
+	"strings"
+)
+
+const (
+	thinkOpenTag  = "<think>"
+	thinkCloseTag = "</think>"
+
+	maxHeldThinkBytes = 2048
+)
+
+var reasoningEffortLevels = []string{"minimal", "low", "medium", "high"}
+
+type reasoningHandlerKey struct{}
+
+func WithReasoningHandler(ctx context.Context, handler StreamHandler) context.Context {
+	return context.WithValue(ctx, reasoningHandlerKey{}, handler)
+}
+
+func reasoningHandlerFrom(ctx context.Context) StreamHandler {
+	handler, _ := ctx.Value(reasoningHandlerKey{}).(StreamHandler)
+	return handler
+}
+
+func IsReasoningEffort(effort string) bool {
+	for _, level := range reasoningEffortLevels {
+		if effort == level {
+			return true
+		}
+	}
+	return false
+}
+
+type thinkTagMode int
+
+const (
+	thinkTagsNone thinkTagMode = iota
+	thinkTagsLeading
+	thinkTagsUnopened
+)
+
+var leadingThinkModels = []string{"qwen3", "magistral", "phi-4-reasoning", "exaone-deep", "cogito", "openthinker",
+	"deepscaler", "glm-z1", "minimax-m1"}
+
+func thinkTagsFor(model string, hostsOpenModels bool) thinkTagMode {
+	name := normalizeModelName(model)
+	if strings.Contains(name, "deepseek-r1") || strings.Contains(name, "qwq") ||
+		(strings.Contains(name, "qwen3") && strings.Contains(name, "thinking")) {
+		return thinkTagsUnopened
+	}
+	for _, prefix := range leadingThinkModels {
+		if strings.Contains(name, prefix) {
+			return thinkTagsLeading
+		}
+	}
+	if hostsOpenModels {
+		return thinkTagsLeading
+	}
+	return thinkTagsNone
+}
+
+func splitReasoning(text string, tags thinkTagMode) (answer string, reasoning string) {
+	if tags == thinkTagsNone {
+		return strings.TrimSpace(text), ""
+	}
+
+	if rest, ok := strings.CutPrefix(strings.TrimLeft(text, " \t\r\n"), thinkOpenTag); ok {
+		thought, answer, closed := strings.Cut(rest, thinkCloseTag)
+		if !closed {
+			return "", strings.TrimSpace(rest)
+		}
+		return strings.TrimSpace(answer), strings.TrimSpace(thought)
+	}
+
+	if tags == thinkTagsUnopened {
+		if thought, answer, ok := strings.Cut(text, thinkCloseTag); ok && !strings.Contains(thought, thinkOpenTag) {
+			return strings.TrimSpace(answer), strings.TrimSpace(thought)
+		}
+	}
+	return strings.TrimSpace(text), ""
+}
+
+type thinkState int
+
+const (
+	thinkUndecided thinkState = iota
+	thinkInside
+	thinkHeld
+	thinkDone
+)
+
+type reasoningStream struct {
+	onAnswer    StreamHandler
+	onReasoning StreamHandler
+	tags        thinkTagMode
+
+	raw      strings.Builder
+	native   strings.Builder
+	pending  string
+	state    thinkState
+	answered bool
+}
+
+func newReasoningStream(ctx context.Context, onChunk StreamHandler, tags thinkTagMode) *reasoningStream {
+	return &reasoningStream{
+		onAnswer:    onChunk,
+		onReasoning: reasoningHandlerFrom(ctx),
+		tags:        tags,
+	}
+}
+
+func (s *reasoningStream) Reasoning(chunk string) {
+	if chunk == "" {
+		return
+	}
+	s.native.WriteString(chunk)
+	if s.onReasoning != nil {
+		s.onReasoning(chunk)
+	}
+}
+
+func (s *reasoningStream) Text(chunk string) {
+	if chunk == "" {
+		return
+	}
+	s.raw.WriteString(chunk)
+	if s.tags == thinkTagsNone || s.state == thinkDone {
+		s.emit(chunk)
+		return
+	}
+	s.pending += chunk
+
+	for {
+		switch s.state {
+		case thinkUndecided:
+			trimmed := strings.TrimLeft(s.pending, " \t\r\n")
+			if rest, ok := strings.CutPrefix(trimmed, thinkOpenTag); ok {
+				s.pending = rest
+				s.state = thinkInside
+				continue
+			}
+			if strings.HasPrefix(thinkOpenTag, trimmed) {
+				return
+			}
+			if s.tags == thinkTagsUnopened {
+				s.state = thinkHeld
+				continue
+			}
+			s.answer(s.pending)
+			return
+
+		case thinkInside:
+			if thought, answer, ok := strings.Cut(s.pending, thinkCloseTag); ok {
+				s.think(thought)
+				s.answer(answer)
+				return
+			}
+			held := partialTagSuffix(s.pending, thinkCloseTag)
+			s.think(s.pending[:len(s.pending)-held])
+			s.pending = s.pending[len(s.pending)-held:]
+			return
+
+		case thinkHeld:
+			thought, answer, ok := strings.Cut(s.pending, thinkCloseTag)
+			if strings.Contains(thought, thinkOpenTag) {
+				s.answer(s.pending)
+				return
+			}
+			if ok {
+				s.think(thought)
+				s.answer(answer)
+			} else if len(s.pending) > maxHeldThinkBytes {
+				s.answer(s.pending)
+			}
+			return
+		}
+	}
+}
+
+func (s *reasoningStream) think(text string) {
+	if text != "" && s.onReasoning != nil {
+		s.onReasoning(text)
+	}
+}
+
+func (s *reasoningStream) answer(text string) {
+	s.state = thinkDone
+	s.pending = ""
+	s.emit(text)
+}
+
+func (s *reasoningStream) emit(text string) {
+	if text == "" {
+		return
+	}
+	if !s.answered {
+		text = strings.TrimLeft(text, " \t\r\n")
+		if text == "" {
+			return
+		}
+		s.answered = true
+	}
+	if s.onAnswer != nil {
+		s.onAnswer(text)
+	}
+}
+
+func (s *reasoningStream) Response(usage Usage) *Response {
+	if s.state == thinkInside {
+		s.think(s.pending)
+		s.pending = ""
+	} else if s.state != thinkDone {
+		s.answer(s.pending)
+	}
+
+	answer, reasoning := splitReasoning(s.raw.String(), s.tags)
+	if native := strings.TrimSpace(s.native.String()); native != "" {
+		reasoning = strings.TrimSpace(native + "\n\n" + reasoning)
+	}
+	return &Response{Text: answer, Reasoning: reasoning, Usage: usage}
+}
+
+func newReasoningResponse(text, nativeReasoning string, usage Usage, tags thinkTagMode) *Response {
+	answer, reasoning := splitReasoning(text, tags)
+	if native := strings.TrimSpace(nativeReasoning); native != "" {
+		reasoning = strings.TrimSpace(native + "\n\n" + reasoning)
+	}
+	return &Response{Text: answer, Reasoning: reasoning, Usage: usage}
+}
+
+func partialTagSuffix(text, tag string) int {
+	for n := len(tag) - 1; n > 0; n-- {
+		if strings.HasSuffix(text, tag[:n]) {
+			return n
+		}
+	}
+	return 0
+}
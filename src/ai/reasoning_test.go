@@ -0,0 +1,92 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestSplitReasoning(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		tags      thinkTagMode
+		answer    string
+		reasoning string
+	}{
+		{"leading block", "\n<think>plan</think>\n\nfeat: add x", thinkTagsLeading, "feat: add x", "plan"},
+		{"unclosed block", "<think>still planning", thinkTagsLeading, "", "still planning"},
+		{"tags later in the answer", "Wrap it in <think>...</think> tags.", thinkTagsLeading, "Wrap it in <think>...</think> tags.", ""},
+		{"stray close tag", "keep this </think> and this", thinkTagsLeading, "keep this </think> and this", ""},
+		{"unopened block", "plan</think>feat: add x", thinkTagsUnopened, "feat: add x", "plan"},
+		{"provider without tags", "<think>literal</think> text", thinkTagsNone, "<think>literal</think> text", ""},
+	}
+	for _, tt := range tests {
+		answer, reasoning := splitReasoning(tt.text, tt.tags)
+		if answer != tt.answer || reasoning != tt.reasoning {
+			t.Errorf("%s: got (%q, %q), want (%q, %q)", tt.name, answer, reasoning, tt.answer, tt.reasoning)
+		}
+	}
+}
+
+func TestReasoningStream(t *testing.T) {
+	tests := []struct {
+		name      string
+		chunks    []string
+		tags      thinkTagMode
+		streamed  string
+		reasoning string
+	}{
+		{"leading block", []string{" <thi", "nk>pl", "an</thi", "nk>\nfeat", ": add x"}, thinkTagsLeading, "feat: add x", "plan"},
+		{"tags later in the answer", []string{"use <think>", " tags</think> here"}, thinkTagsLeading, "use <think> tags</think> here", ""},
+		{"unopened block", []string{"pl", "an</th", "ink>feat: add x"}, thinkTagsUnopened, "feat: add x", "plan"},
+		{"unopened without close tag", []string{"feat: ", "add x"}, thinkTagsUnopened, "feat: add x", ""},
+		{"provider without tags", []string{"<think>a</think>b"}, thinkTagsNone, "<think>a</think>b", ""},
+	}
+	for _, tt := range tests {
+		var streamed, thoughts strings.Builder
+		ctx := WithReasoningHandler(context.Background(), func(chunk string) { thoughts.WriteString(chunk) })
+		stream := newReasoningStream(ctx, func(chunk string) { streamed.WriteString(chunk) }, tt.tags)
+		for _, chunk := range tt.chunks {
+			stream.Text(chunk)
+		}
+		resp := stream.Response(Usage{})
+		if streamed.String() != tt.streamed || resp.Text != tt.streamed {
+			t.Errorf("%s: streamed %q, response %q, want %q", tt.name, streamed.String(), resp.Text, tt.streamed)
+		}
+		if thoughts.String() != tt.reasoning || resp.Reasoning != tt.reasoning {
+			t.Errorf("%s: streamed reasoning %q, response %q, want %q", tt.name, thoughts.String(), resp.Reasoning, tt.reasoning)
+		}
+	}
+}
+
+func TestReasoningStreamFlushesHeldAnswer(t *testing.T) {
+	var streamed strings.Builder
+	stream := newReasoningStream(context.Background(), func(chunk string) { streamed.WriteString(chunk) }, thinkTagsUnopened)
+
+	stream.Text("feat: add x\n\n")
+	if streamed.Len() != 0 {
+		t.Fatalf("a short prefix without </think> should be held, streamed %q", streamed.String())
+	}
+	body := strings.Repeat("- explain the change\n", maxHeldThinkBytes/20)
+	stream.Text(body)
+	if want := "feat: add x\n\n" + body; streamed.String() != want {
+		t.Fatalf("streamed %q before EOF, want the held answer", streamed.String())
+	}
+	stream.Text("done")
+	if resp := stream.Response(Usage{}); !strings.HasSuffix(streamed.String(), "done") || resp.Reasoning != "" {
+		t.Errorf("streamed %q, reasoning %q", streamed.String(), resp.Reasoning)
+	}
+}
+
+func TestThinkTagsFor(t *testing.T) {
+	if thinkTagsFor("claude-sonnet-4-5", false) != thinkTagsNone {
+		t.Error("native providers should not strip think tags from other models")
+	}
+	if thinkTagsFor("deepseek-ai/DeepSeek-R1-Distill-Qwen-32B", false) != thinkTagsUnopened {
+		t.Error("DeepSeek R1 models may omit the opening tag")
+	}
+	if thinkTagsFor("llama3.2", true) != thinkTagsLeading {
+		t.Error("hosts of open models should strip a leading think block")
+	}
+}
@@ -7,38 +7,81 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"time"
 )
 
-func doAPIRequest(ctx context.Context, client *http.Client, method, url string, headers http.Header, payload interface{}) ([]byte, int, error) {
+func marshalPayload(payload interface{}) ([]byte, error) {
+	if payload == nil {
+		return nil, nil
+	}
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request payload: %w", err)
+	}
+	return jsonPayload, nil
+}
+
+func sendRequest(ctx context.Context, client *http.Client, method, url string, headers http.Header, body []byte) (*http.Response, error) {
 	var reqBody io.Reader
-	if payload != nil {
-		jsonPayload, err := json.Marshal(payload)
-		if err != nil {
-			return nil, 0, fmt.Errorf("failed to marshal request payload: %w", err)
-		}
-		reqBody = bytes.NewBuffer(jsonPayload)
+	if body != nil {
+		reqBody = bytes.NewReader(body)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to create http request: %w", err)
+		return nil, fmt.Errorf("failed to create http request: %w", err)
 	}
 	if headers != nil {
-		req.Header = headers
+		req.Header = headers.Clone()
 	}
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to send request: %w", err)
+		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
-	defer func() {
-		_ = resp.Body.Close()
-	}()
+	return resp, nil
+}
 
-	respBody, err := io.ReadAll(resp.Body)
+func doAPIRequest(ctx context.Context, client *http.Client, method, url string, headers http.Header, payload interface{}) ([]byte, int, error) {
+	body, err := marshalPayload(payload)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to read response body: %w", err)
+		return nil, 0, err
+	}
+
+	var respBody []byte
+	var statusCode int
+	var lastErr error
+
+	retryErr := retryLoop(ctx, func() (bool, time.Duration, error) {
+		respBody, statusCode, lastErr = nil, 0, nil
+
+		resp, err := sendRequest(ctx, client, method, url, headers, body)
+		if err != nil {
+			lastErr = err
+			return !isRetryableError(ctx, err), 0, err
+		}
+		defer func() {
+			_ = resp.Body.Close()
+		}()
+
+		respBody, err = io.ReadAll(resp.Body)
+		if err != nil {
+			lastErr = fmt.Errorf("failed to read response body: %w", err)
+			return !isRetryableError(ctx, err), 0, lastErr
+		}
+		statusCode = resp.StatusCode
+
+		if !isRetryableStatus(statusCode, respBody) {
+			return true, 0, nil
+		}
+		return false, retryAfterFromHeaders(statusCode, resp.Header), statusError(statusCode)
+	})
+	if retryErr != nil {
+		return nil, 0, fmt.Errorf("request aborted while waiting to retry: %w", retryErr)
+	}
+	if lastErr != nil {
+		return nil, 0, lastErr
 	}
 
-	return respBody, resp.StatusCode, nil
+	return respBody, statusCode, nil
 }
@@ -0,0 +1,195 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gct/src/config"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultRetryMaxAttempts  = 4
+	defaultRetryInitialDelay = time.Second
+	defaultRetryMaxDelay     = 30 * time.Second
+)
+
+type RetryPolicy struct {
+	MaxAttempts  int
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+}
+
+type RetryEvent struct {
+	Attempt     int
+	MaxAttempts int
+	Delay       time.Duration
+	Reason      error
+}
+
+type RetryNotifier func(event RetryEvent)
+
+type retryPolicyKey struct{}
+type retryNotifierKey struct{}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:  defaultRetryMaxAttempts,
+		InitialDelay: defaultRetryInitialDelay,
+		MaxDelay:     defaultRetryMaxDelay,
+	}
+}
+
+func NewRetryPolicy(cfg config.RetryConfig) RetryPolicy {
+	policy := DefaultRetryPolicy()
+	if cfg.MaxAttempts > 0 {
+		policy.MaxAttempts = cfg.MaxAttempts
+	}
+	if cfg.InitialDelay > 0 {
+		policy.InitialDelay = cfg.InitialDelay
+	}
+	if cfg.MaxDelay > 0 {
+		policy.MaxDelay = cfg.MaxDelay
+	}
+	return policy
+}
+
+func WithRetryPolicy(ctx context.Context, policy RetryPolicy) context.Context {
+	return context.WithValue(ctx, retryPolicyKey{}, policy)
+}
+
+func WithRetryNotifier(ctx context.Context, notifier RetryNotifier) context.Context {
+	return context.WithValue(ctx, retryNotifierKey{}, notifier)
+}
+
+func retryPolicyFromContext(ctx context.Context) RetryPolicy {
+	if policy, ok := ctx.Value(retryPolicyKey{}).(RetryPolicy); ok {
+		return policy
+	}
+	return DefaultRetryPolicy()
+}
+
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.InitialDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+func retryLoop(ctx context.Context, attempt func() (done bool, retryAfter time.Duration, reason error)) error {
+	policy := retryPolicyFromContext(ctx)
+	notifier, _ := ctx.Value(retryNotifierKey{}).(RetryNotifier)
+
+	for n := 1; ; n++ {
+		done, retryAfter, reason := attempt()
+		if done || n >= policy.MaxAttempts || retryAfter > policy.MaxDelay {
+			return nil
+		}
+
+		delay := retryAfter
+		if delay <= 0 {
+			delay = policy.backoff(n)
+		}
+
+		if notifier != nil {
+			notifier(RetryEvent{
+				Attempt:     n + 1,
+				MaxAttempts: policy.MaxAttempts,
+				Delay:       delay,
+				Reason:      reason,
+			})
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func isRetryableStatus(statusCode int, respBody []byte) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests:
+		return !strings.Contains(string(respBody), "insufficient_quota")
+	case http.StatusRequestTimeout, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout, 529:
+		return true
+	}
+	return false
+}
+
+func isRetryableError(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+func retryAfterFromHeaders(statusCode int, headers http.Header) time.Duration {
+	if headers == nil {
+		return 0
+	}
+
+	if ms := headers.Get("retry-after-ms"); ms != "" {
+		if v, err := strconv.ParseFloat(ms, 64); err == nil && v > 0 {
+			return time.Duration(v * float64(time.Millisecond))
+		}
+	}
+
+	if ra := headers.Get("Retry-After"); ra != "" {
+		if secs, err := strconv.ParseFloat(ra, 64); err == nil && secs > 0 {
+			return time.Duration(secs * float64(time.Second))
+		}
+		if at, err := http.ParseTime(ra); err == nil {
+			if d := time.Until(at); d > 0 {
+				return d
+			}
+		}
+	}
+
+	if statusCode != http.StatusTooManyRequests {
+		return 0
+	}
+
+	var longest time.Duration
+	for _, name := range []string{"x-ratelimit-reset-requests", "x-ratelimit-reset-tokens"} {
+		if v := headers.Get(name); v != "" {
+			if d, err := time.ParseDuration(v); err == nil && d > longest {
+				longest = d
+			}
+		}
+	}
+	for _, name := range []string{"anthropic-ratelimit-requests-reset", "anthropic-ratelimit-tokens-reset", "anthropic-ratelimit-input-tokens-reset", "anthropic-ratelimit-output-tokens-reset"} {
+		if v := headers.Get(name); v != "" {
+			if at, err := time.Parse(time.RFC3339, v); err == nil {
+				if d := time.Until(at); d > longest {
+					longest = d
+				}
+			}
+		}
+	}
+	return longest
+}
+
+func statusError(statusCode int) error {
+	return fmt.Errorf("server returned %d %s", statusCode, http.StatusText(statusCode))
+}
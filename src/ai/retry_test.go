@@ -0,0 +1,42 @@
+package ai
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestIsRetryableError(t *testing.T) {
+	dial := func(err error) error {
+		return &url.Error{Op: "Post", URL: "https://api.example.com", Err: &net.OpError{Op: "dial", Net: "tcp", Err: err}}
+	}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"idle timeout", &idleTimeoutError{timeout: time.Second}, true},
+		{"dial timeout", dial(os.ErrDeadlineExceeded), true},
+		{"connection reset", dial(os.NewSyscallError("read", syscall.ECONNRESET)), true},
+		{"truncated body", fmt.Errorf("failed to read response body: %w", io.ErrUnexpectedEOF), true},
+		{"connection refused", dial(os.NewSyscallError("connect", syscall.ECONNREFUSED)), false},
+		{"unknown host", dial(&net.DNSError{Err: "no such host", Name: "api.example.com", IsNotFound: true}), false},
+		{"untrusted certificate", &url.Error{Op: "Post", URL: "https://api.example.com", Err: x509.UnknownAuthorityError{}}, false},
+		{"cancelled", context.Canceled, false},
+		{"missing fixture", errFixtureNotFound, false},
+		{"other", errors.New("boom"), false},
+	}
+	for _, tt := range tests {
+		if got := isRetryableError(context.Background(), tt.err); got != tt.want {
+			t.Errorf("%s: isRetryableError(%v) = %v, want %v", tt.name, tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type ResponseSchema struct {
+	Name   string
+	Schema map[string]interface{}
+}
+
+type jsonMode int
+
+const (
+	jsonModeNone jsonMode = iota
+	jsonModeObject
+	jsonModeSchema
+)
+
+type openAIResponseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *openAIJSONSchema `json:"json_schema,omitempty"`
+}
+
+type openAIJSONSchema struct {
+	Name   string                 `json:"name"`
+	Schema map[string]interface{} `json:"schema"`
+	Strict bool                   `json:"strict"`
+}
+
+func newOpenAIResponseFormat(schema *ResponseSchema, mode jsonMode) *openAIResponseFormat {
+	if schema == nil || mode == jsonModeNone {
+		return nil
+	}
+	if mode == jsonModeObject {
+		return &openAIResponseFormat{Type: "json_object"}
+	}
+	return &openAIResponseFormat{
+		Type: "json_schema",
+		JSONSchema: &openAIJSONSchema{
+			Name:   schema.Name,
+			Schema: schema.Schema,
+			Strict: true,
+		},
+	}
+}
+
+func geminiSchema(schema map[string]interface{}) map[string]interface{} {
+	converted := make(map[string]interface{}, len(schema))
+	for key, value := range schema {
+		switch key {
+		case "additionalProperties":
+			continue
+		case "properties":
+			properties := make(map[string]interface{})
+			for name, property := range value.(map[string]interface{}) {
+				properties[name] = geminiSchema(property.(map[string]interface{}))
+			}
+			converted[key] = properties
+		case "items":
+			converted[key] = geminiSchema(value.(map[string]interface{}))
+		default:
+			converted[key] = value
+		}
+	}
+	return converted
+}
+
+func ExtractJSON(text string) string {
+	text = strings.TrimSpace(text)
+	start := strings.Index(text, "{")
+	end := strings.LastIndex(text, "}")
+	if start < 0 || end < start {
+		return text
+	}
+	return text[start : end+1]
+}
+
+func (s *ResponseSchema) Decode(text string, v interface{}) error {
+	raw := ExtractJSON(text)
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		return fmt.Errorf("response is not valid JSON: %w", err)
+	}
+	if err := validateSchemaValue(s.Schema, value, "response"); err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(raw), v); err != nil {
+		return fmt.Errorf("response does not match the expected shape: %w", err)
+	}
+	return nil
+}
+
+func validateSchemaValue(schema map[string]interface{}, value interface{}, path string) error {
+	schemaType, _ := schema["type"].(string)
+
+	switch schemaType {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s must be an object", path)
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+
+		if required, ok := schema["required"].([]string); ok {
+			for _, name := range required {
+				if _, found := object[name]; !found {
+					return fmt.Errorf("%s is missing required field '%s'", path, name)
+				}
+			}
+		}
+
+		names := make([]string, 0, len(object))
+		for name := range object {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			property, known := properties[name].(map[string]interface{})
+			if !known {
+				if allowed, ok := schema["additionalProperties"].(bool); ok && !allowed {
+					return fmt.Errorf("%s has unexpected field '%s'", path, name)
+				}
+				continue
+			}
+			if err := validateSchemaValue(property, object[name], path+"."+name); err != nil {
+				return err
+			}
+		}
+
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s must be an array", path)
+		}
+		itemSchema, _ := schema["items"].(map[string]interface{})
+		for i, item := range items {
+			if itemSchema == nil {
+				break
+			}
+			if err := validateSchemaValue(itemSchema, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+
+	case "string":
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("%s must be a string", path)
+		}
+
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s must be a boolean", path)
+		}
+
+	case "number", "integer":
+		number, ok := value.(float64)
+		if !ok {
+			return fmt.Errorf("%s must be a number", path)
+		}
+		if schemaType == "integer" && number != float64(int64(number)) {
+			return fmt.Errorf("%s must be an integer", path)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const maxSSELineSize = 1024 * 1024
+
+func doStreamRequest(ctx context.Context, client *http.Client, method, url string, headers http.Header, payload interface{}) (*http.Response, error) {
+	body, err := marshalPayload(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	streamHeaders := http.Header{}
+	if headers != nil {
+		streamHeaders = headers.Clone()
+	}
+	streamHeaders.Set("Accept", "text/event-stream")
+
+	var resp *http.Response
+	var lastErr error
+
+	retryErr := retryLoop(ctx, func() (bool, time.Duration, error) {
+		resp, lastErr = nil, nil
+
+		r, err := sendRequest(ctx, client, method, url, streamHeaders, body)
+		if err != nil {
+			lastErr = err
+			return !isRetryableError(ctx, err), 0, err
+		}
+		if r.StatusCode == http.StatusOK {
+			resp = r
+			return true, 0, nil
+		}
+
+		respBody, _ := io.ReadAll(r.Body)
+		_ = r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(respBody))
+		resp = r
+
+		if !isRetryableStatus(r.StatusCode, respBody) {
+			return true, 0, nil
+		}
+		return false, retryAfterFromHeaders(r.StatusCode, r.Header), statusError(r.StatusCode)
+	})
+	if retryErr != nil {
+		return nil, fmt.Errorf("request aborted while waiting to retry: %w", retryErr)
+	}
+	if lastErr != nil {
+		return nil, lastErr
+	}
+
+	return resp, nil
+}
+
+func readSSE(r io.Reader, onEvent func(event, data string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxSSELineSize)
+
+	var event string
+	var data strings.Builder
+
+	dispatch := func() error {
+		if data.Len() == 0 {
+			event = ""
+			return nil
+		}
+		payload := strings.TrimSuffix(data.String(), "\n")
+		err := onEvent(event, payload)
+		event = ""
+		data.Reset()
+		return err
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if err := dispatch(); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, ":"):
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+			data.WriteString("\n")
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read event stream: %w", err)
+	}
+	return dispatch()
+}
+
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+func (u *openAIUsage) toUsage() Usage {
+	if u == nil {
+		return Usage{}
+	}
+	return Usage{InputTokens: u.PromptTokens, OutputTokens: u.CompletionTokens}
+}
+
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type openAIStreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content          string `json:"content"`
+			ReasoningContent string `json:"reasoning_content"`
+			Reasoning        string `json:"reasoning"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Usage *openAIUsage `json:"usage,omitempty"`
+	Error *struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+	} `json:"error,omitempty"`
+}
+
+func readOpenAIStream(ctx context.Context, provider string, body io.Reader, onChunk StreamHandler, tags thinkTagMode) (*Response, error) {
+	stream := newReasoningStream(ctx, onChunk, tags)
+	var usage Usage
+
+	err := readSSE(body, func(_, data string) error {
+		if data == "[DONE]" {
+			return nil
+		}
+
+		var chunk openAIStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("failed to parse stream chunk: %w", err)
+		}
+		if chunk.Error != nil {
+			return newAPIError(provider, "", 0, []byte(chunk.Error.Type), fmt.Sprintf("stream error (type: %s): %s", chunk.Error.Type, chunk.Error.Message))
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage.toUsage()
+		}
+		if len(chunk.Choices) == 0 {
+			return nil
+		}
+
+		delta := chunk.Choices[0].Delta
+		stream.Reasoning(delta.ReasoningContent + delta.Reasoning)
+		stream.Text(delta.Content)
+		return nil
+	})
+
+	return stream.Response(usage), err
+}
@@ -0,0 +1,187 @@
+package ai
+
+import (
+	"bufio"
+	"compress/gzip"
+	"embed"
+	"encoding/base64"
+	"fmt"
+	"gct/src/config"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/pkoukk/tiktoken-go"
+)
+
+const (
+	encodingO200K  = "o200k_base"
+	encodingCL100K = "cl100k_base"
+)
+
+const (
+	tokensPerMessage = 4
+	tokensPerReply   = 3
+)
+
+type TokenCount struct {
+	Tokens   int
+	Exact    bool
+	Encoding string
+}
+
+type tokenHeuristic struct {
+	bytesPerToken float64
+	runesPerToken float64
+}
+
+var (
+	anthropicHeuristic = tokenHeuristic{bytesPerToken: 3.5, runesPerToken: 1.0}
+	geminiHeuristic    = tokenHeuristic{bytesPerToken: 4.0, runesPerToken: 1.4}
+	defaultHeuristic   = tokenHeuristic{bytesPerToken: 3.7, runesPerToken: 1.1}
+)
+
+//go:embed encodings/*.tiktoken.gz
+var encodingFiles embed.FS
+
+var (
+	encodersMu sync.Mutex
+	encoders   = map[string]*tiktoken.Tiktoken{}
+)
+
+func init() {
+	tiktoken.SetBpeLoader(embeddedBpeLoader{})
+}
+
+type embeddedBpeLoader struct{}
+
+func (embeddedBpeLoader) LoadTiktokenBpe(file string) (map[string]int, error) {
+	f, err := encodingFiles.Open("encodings/" + path.Base(file) + ".gz")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+
+	ranks := make(map[string]int)
+	scanner := bufio.NewScanner(zr)
+	for scanner.Scan() {
+		token, rank, ok := strings.Cut(scanner.Text(), " ")
+		if !ok {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(token)
+		if err != nil {
+			return nil, fmt.Errorf("invalid token in %s: %w", path.Base(file), err)
+		}
+		n, err := strconv.Atoi(rank)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rank in %s: %w", path.Base(file), err)
+		}
+		ranks[string(decoded)] = n
+	}
+	return ranks, scanner.Err()
+}
+
+func CountTokens(provider config.ProviderConfig, messages []Message) TokenCount {
+	var count TokenCount
+	for _, msg := range messages {
+		tokens, exact, encoding := countText(provider, msg.Content)
+		count.Tokens += tokens
+		count.Exact, count.Encoding = exact, encoding
+	}
+	if count.Exact {
+		count.Tokens += len(messages)*tokensPerMessage + tokensPerReply
+	}
+	return count
+}
+
+func CountTextTokens(provider config.ProviderConfig, text string) int {
+	tokens, _, _ := countText(provider, text)
+	return tokens
+}
+
+func countText(provider config.ProviderConfig, text string) (int, bool, string) {
+	if encoding := tokenizerEncoding(provider.Model); encoding != "" {
+		if encoder, err := loadEncoder(encoding); err == nil {
+			return len(encoder.EncodeOrdinary(text)), true, encoding
+		}
+	}
+	return heuristicFor(provider).estimate(text), false, ""
+}
+
+func normalizeModelName(model string) string {
+	name := strings.ToLower(strings.TrimSpace(model))
+	if idx := strings.LastIndex(name, "/"); idx >= 0 {
+		name = name[idx+1:]
+	}
+	return name
+}
+
+func tokenizerEncoding(model string) string {
+	name := normalizeModelName(model)
+	for _, prefix := range []string{"gpt-4o", "chatgpt-4o", "gpt-4.1", "gpt-4.5", "gpt-5", "gpt-oss", "o1", "o3", "o4"} {
+		if strings.HasPrefix(name, prefix) {
+			return encodingO200K
+		}
+	}
+	for _, prefix := range []string{"gpt-4", "gpt-3.5", "gpt-35", "text-embedding-3", "text-embedding-ada"} {
+		if strings.HasPrefix(name, prefix) {
+			return encodingCL100K
+		}
+	}
+	return ""
+}
+
+func loadEncoder(encoding string) (*tiktoken.Tiktoken, error) {
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+
+	if encoder, ok := encoders[encoding]; ok {
+		return encoder, nil
+	}
+	encoder, err := tiktoken.GetEncoding(encoding)
+	if err != nil {
+		return nil, err
+	}
+	encoders[encoding] = encoder
+	return encoder, nil
+}
+
+func heuristicFor(provider config.ProviderConfig) tokenHeuristic {
+	name := normalizeModelName(provider.Model)
+	switch {
+	case strings.Contains(name, "claude"):
+		return anthropicHeuristic
+	case strings.Contains(name, "gemini"), strings.Contains(name, "gemma"):
+		return geminiHeuristic
+	}
+
+	switch strings.ToLower(strings.ReplaceAll(provider.Provider, " ", "")) {
+	case "anthropic":
+		return anthropicHeuristic
+	case "googleaistudio", "google", "gemini", "googlevertexai", "vertexai", "vertex":
+		return geminiHeuristic
+	}
+	return defaultHeuristic
+}
+
+func (h tokenHeuristic) estimate(text string) int {
+	asciiBytes, otherRunes := 0, 0
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			asciiBytes++
+		} else {
+			otherRunes++
+		}
+	}
+	tokens := float64(asciiBytes)/h.bytesPerToken + float64(otherRunes)/h.runesPerToken
+	return int(tokens + 0.5)
+}
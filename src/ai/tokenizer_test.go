@@ -0,0 +1,24 @@
+package ai
+
+import (
+	"gct/src/config"
+	"testing"
+)
+
+func TestCountTokens(t *testing.T) {
+	messages := UserPrompt("hello world")
+
+	openai := CountTokens(config.ProviderConfig{Provider: "OpenAI", Model: "gpt-4o"}, messages)
+	if !openai.Exact || openai.Encoding != encodingO200K || openai.Tokens != 2+tokensPerMessage+tokensPerReply {
+		t.Errorf("gpt-4o: got %+v, want an exact o200k count with the chat overhead", openai)
+	}
+	legacy := CountTokens(config.ProviderConfig{Provider: "OpenAI", Model: "gpt-3.5-turbo"}, messages)
+	if !legacy.Exact || legacy.Encoding != encodingCL100K {
+		t.Errorf("gpt-3.5-turbo: got %+v, want an exact cl100k count", legacy)
+	}
+
+	claude := CountTokens(config.ProviderConfig{Provider: "Anthropic", Model: "claude-sonnet-4-5"}, messages)
+	if want := anthropicHeuristic.estimate("hello world"); claude.Exact || claude.Tokens != want {
+		t.Errorf("claude: got %+v, want an estimate of %d without the OpenAI chat overhead", claude, want)
+	}
+}
@@ -0,0 +1,199 @@
+package ai
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"gct/src/config"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultRequestTimeout = 90 * time.Second
+	localRequestTimeout   = 10 * time.Minute
+	defaultConnectTimeout = 30 * time.Second
+)
+
+type headerTransport struct {
+	base    http.RoundTripper
+	headers map[string]string
+	host    atomic.Pointer[string]
+}
+
+func newHeaderTransport(base http.RoundTripper, headers map[string]string, baseURL string) *headerTransport {
+	t := &headerTransport{base: base, headers: headers}
+	t.scope(baseURL)
+	return t
+}
+
+func (t *headerTransport) scope(baseURL string) {
+	if u, err := url.Parse(baseURL); err == nil && u.Host != "" {
+		t.host.Store(&u.Host)
+	}
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if host := t.host.Load(); host == nil || !strings.EqualFold(req.URL.Host, *host) {
+		return t.base.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	for key, value := range t.headers {
+		req.Header.Set(key, value)
+	}
+	return t.base.RoundTrip(req)
+}
+
+func newHTTPClient(cfg config.HTTPConfig, defaultTimeout time.Duration, secretParams ...string) (*http.Client, *headerTransport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	connectTimeout := defaultConnectTimeout
+	if cfg.ConnectTimeout > 0 {
+		connectTimeout = cfg.ConnectTimeout
+	}
+	transport.DialContext = (&net.Dialer{
+		Timeout:   connectTimeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.TLSHandshakeTimeout = connectTimeout
+
+	if cfg.Proxy != "" {
+		proxyURL, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid http.proxy URL: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if cfg.CAFile != "" || cfg.ClientCert != "" || cfg.ClientKey != "" {
+		tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+		if cfg.CAFile != "" {
+			pem, err := os.ReadFile(cfg.CAFile)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to read http.ca_file: %w", err)
+			}
+			pool, err := x509.SystemCertPool()
+			if err != nil || pool == nil {
+				pool = x509.NewCertPool()
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, nil, fmt.Errorf("no PEM certificates found in http.ca_file %s", cfg.CAFile)
+			}
+			tlsConfig.RootCAs = pool
+		}
+
+		if cfg.ClientCert != "" || cfg.ClientKey != "" {
+			if cfg.ClientCert == "" || cfg.ClientKey == "" {
+				return nil, nil, fmt.Errorf("http.client_cert and http.client_key must be set together")
+			}
+			cert, err := tls.LoadX509KeyPair(cfg.ClientCert, cfg.ClientKey)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to load client certificate: %w", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	var roundTripper http.RoundTripper = transport
+	var headers *headerTransport
+	if len(cfg.Headers) > 0 {
+		headers = newHeaderTransport(transport, cfg.Headers, "")
+		roundTripper = headers
+	}
+	roundTripper, err := newFixtureTransport(roundTripper, cfg.Record, cfg.Replay, secretParams)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	timeout := defaultTimeout
+	if cfg.Timeout > 0 {
+		timeout = cfg.Timeout
+	}
+
+	return &http.Client{Transport: &idleTimeoutTransport{base: roundTripper, timeout: timeout}}, headers, nil
+}
+
+func httpClientOrDefault(client *http.Client, timeout time.Duration) *http.Client {
+	if client != nil {
+		return client
+	}
+	return &http.Client{Transport: &idleTimeoutTransport{base: http.DefaultTransport, timeout: timeout}}
+}
+
+type idleTimeoutError struct {
+	timeout time.Duration
+}
+
+func (e *idleTimeoutError) Error() string {
+	return fmt.Sprintf("no data received for %s", e.timeout)
+}
+
+func (e *idleTimeoutError) Timeout() bool {
+	return true
+}
+
+func (e *idleTimeoutError) Temporary() bool {
+	return true
+}
+
+type idleTimeoutTransport struct {
+	base    http.RoundTripper
+	timeout time.Duration
+}
+
+func (t *idleTimeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancelCause(req.Context())
+	timeoutErr := &idleTimeoutError{timeout: t.timeout}
+	timer := time.AfterFunc(t.timeout, func() { cancel(timeoutErr) })
+
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		timer.Stop()
+		cancel(nil)
+		if context.Cause(ctx) == timeoutErr {
+			return nil, timeoutErr
+		}
+		return nil, err
+	}
+	resp.Body = &idleTimeoutBody{ReadCloser: resp.Body, ctx: ctx, cancel: cancel, timer: timer, timeout: t.timeout}
+	return resp, nil
+}
+
+type idleTimeoutBody struct {
+	io.ReadCloser
+	ctx     context.Context
+	cancel  context.CancelCauseFunc
+	timer   *time.Timer
+	timeout time.Duration
+}
+
+func (b *idleTimeoutBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.timer.Reset(b.timeout)
+	}
+	if err != nil && err != io.EOF {
+		var timeoutErr *idleTimeoutError
+		if errors.As(context.Cause(b.ctx), &timeoutErr) {
+			return n, timeoutErr
+		}
+	}
+	return n, err
+}
+
+func (b *idleTimeoutBody) Close() error {
+	b.timer.Stop()
+	b.cancel(nil)
+	return b.ReadCloser.Close()
+}
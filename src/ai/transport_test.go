@@ -0,0 +1,87 @@
+package ai
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gct/src/config"
+)
+
+func TestHTTPClientTimeoutIsIdleTime(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/stall" {
+			time.Sleep(400 * time.Millisecond)
+		}
+		for i := 0; i < 6; i++ {
+			w.Write([]byte("data\n"))
+			w.(http.Flusher).Flush()
+			time.Sleep(50 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	recorded := config.HTTPConfig{Timeout: 150 * time.Millisecond, Record: t.TempDir()}
+	for _, cfg := range []config.HTTPConfig{recorded, {Timeout: 150 * time.Millisecond}} {
+		client, _, err := newHTTPClient(cfg, defaultRequestTimeout)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp, err := client.Get(server.URL + "/stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil || len(body) != 30 {
+			t.Fatalf("a stream that keeps sending data should not time out (record=%q): %v, %d bytes", cfg.Record, err, len(body))
+		}
+	}
+	if fixtures := readFixtures(t, recorded.Record); !strings.Contains(fixtures, `data\ndata`) {
+		t.Fatalf("the recorded stream is missing from the fixture:\n%s", fixtures)
+	}
+
+	client, _, err := newHTTPClient(config.HTTPConfig{Timeout: 150 * time.Millisecond}, defaultRequestTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Get(server.URL + "/stall")
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Fatalf("expected a timeout while waiting for the response, got %v", err)
+	}
+}
+
+func TestHTTPHeadersOnlyReachProviderHost(t *testing.T) {
+	var apiHeader, tokenHeader string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiHeader = r.Header.Get("X-Gateway-Key")
+	}))
+	defer api.Close()
+	token := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenHeader = r.Header.Get("X-Gateway-Key")
+	}))
+	defer token.Close()
+
+	client, headers, err := newHTTPClient(config.HTTPConfig{Headers: map[string]string{"X-Gateway-Key": "secret"}}, defaultRequestTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	headers.scope(api.URL + "/v1")
+	for _, u := range []string{api.URL + "/v1/chat/completions", token.URL + "/token"} {
+		resp, err := client.Get(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if apiHeader != "secret" || tokenHeader != "" {
+		t.Fatalf("headers should only reach the provider host: api %q, token %q", apiHeader, tokenHeader)
+	}
+}
@@ -4,101 +4,203 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
-	"time"
+	"strings"
 )
 
+const vertexDefaultMaxTokens = 8192
+
 type VertexAIProvider struct {
 	client    *http.Client
 	apiKey    string
+	tokens    *gcpTokenSource
 	model     string
 	projectID string
 	region    string
 	baseURL   string
+	safety    []geminiSafetySetting
 }
 
-type vertexAIRequest struct {
-	Contents         []vertexAIContent `json:"contents"`
-	GenerationConfig vertexAIGenConfig `json:"generation_config"`
-}
-
-type vertexAIContent struct {
-	Role  string         `json:"role"`
-	Parts []vertexAIPart `json:"parts"`
-}
-type vertexAIPart struct {
-	Text string `json:"text"`
-}
-
-type vertexAIGenConfig struct {
-	MaxOutputTokens int `json:"maxOutputTokens"`
-}
+func NewVertexAIProvider(apiKey, credentialsFile, tokenURL, modelName, projectID, region string, safetySettings map[string]string, client *http.Client) (*VertexAIProvider, error) {
+	if modelName == "" || projectID == "" || region == "" {
+		return nil, fmt.Errorf("Model, GCP Project ID, and GCP Region are all required for Vertex AI")
+	}
+	safety, err := newGeminiSafetySettings(safetySettings)
+	if err != nil {
+		return nil, err
+	}
 
-type vertexAIResponse struct {
-	Candidates []struct {
-		Content struct {
-			Parts []struct {
-				Text string `json:"text"`
-			} `json:"parts"`
-		} `json:"content"`
-	} `json:"candidates"`
-	Error *struct {
-		Message string `json:"message"`
-	} `json:"error,omitempty"`
-}
+	client = httpClientOrDefault(client, defaultRequestTimeout)
 
-func NewVertexAIProvider(apiKey, modelName, projectID, region string) (*VertexAIProvider, error) {
-	if apiKey == "" || modelName == "" || projectID == "" || region == "" {
-		return nil, fmt.Errorf("API Key, Model, GCP Project ID, and GCP Region are all required for Vertex AI")
+	var tokens *gcpTokenSource
+	if apiKey == "" {
+		var err error
+		tokens, err = newGCPTokenSource(credentialsFile, tokenURL, client)
+		if err != nil {
+			return nil, &APIError{Provider: "Google Vertex AI", Kind: ErrAuth, Message: fmt.Sprintf("failed to set up Vertex AI authentication: %v", err), Err: err}
+		}
 	}
 
-	url := fmt.Sprintf("https://%s-aiplatform.googleapis.com/v1/projects/%s/locations/%s/publishers/google/models/%s:generateContent",
-		region, projectID, region, modelName)
-
-	return &VertexAIProvider{
-		client: &http.Client{
-			Timeout: 90 * time.Second,
-		},
+	provider := &VertexAIProvider{
+		client:    client,
 		apiKey:    apiKey,
+		tokens:    tokens,
 		model:     modelName,
 		projectID: projectID,
 		region:    region,
-		baseURL:   url,
-	}, nil
+		safety:    safety,
+	}
+	provider.SetBaseURL(fmt.Sprintf("https://%s-aiplatform.googleapis.com/v1", region))
+	return provider, nil
 }
 
-func (p *VertexAIProvider) Generate(ctx context.Context, prompt string) (string, error) {
-	payload := vertexAIRequest{
-		Contents: []vertexAIContent{
-			{Role: "user", Parts: []vertexAIPart{{Text: prompt}}},
-		},
-		GenerationConfig: vertexAIGenConfig{MaxOutputTokens: 8192},
+func (p *VertexAIProvider) SetBaseURL(baseURL string) {
+	p.baseURL = fmt.Sprintf("%s/projects/%s/locations/%s/publishers/google/models/%s",
+		strings.TrimSuffix(baseURL, "/"), p.projectID, p.region, p.model)
+}
+
+func (p *VertexAIProvider) apiBaseURL() string {
+	return p.baseURL
+}
+
+func (p *VertexAIProvider) newRequest(ctx context.Context, req Request) (geminiRequest, http.Header, error) {
+	payload := newGeminiRequest(req, p.safety, vertexDefaultMaxTokens)
+
+	token := p.apiKey
+	if p.tokens != nil {
+		var err error
+		token, err = p.tokens.Token(ctx)
+		if err != nil {
+			return payload, nil, err
+		}
 	}
 
 	headers := http.Header{}
 	headers.Set("Content-Type", "application/json")
-	headers.Set("Authorization", "Bearer "+p.apiKey)
+	headers.Set("Authorization", "Bearer "+token)
+
+	return payload, headers, nil
+}
 
-	respBody, statusCode, err := doAPIRequest(ctx, p.client, "POST", p.baseURL, headers, payload)
+func (p *VertexAIProvider) errorFromResponse(statusCode int, respBody []byte) error {
+	var apiResp geminiResponse
+	if err := json.Unmarshal(respBody, &apiResp); err == nil && apiResp.Error != nil {
+		return newAPIError("Google Vertex AI", p.model, statusCode, respBody, fmt.Sprintf("vertex ai api error: %s", apiResp.Error.Message))
+	}
+	return newAPIError("Google Vertex AI", p.model, statusCode, respBody, fmt.Sprintf("received non-200 status from vertex ai: %d", statusCode))
+}
+
+func (p *VertexAIProvider) Generate(ctx context.Context, req Request) (*Response, error) {
+	payload, headers, err := p.newRequest(ctx, req)
 	if err != nil {
-		return "", fmt.Errorf("failed to send request to vertex ai: %w", err)
+		return nil, err
 	}
 
-	var apiResp vertexAIResponse
-	if err := json.Unmarshal(respBody, &apiResp); err != nil {
-		return "", fmt.Errorf("failed to parse vertex ai json response: %w", err)
+	respBody, statusCode, err := doAPIRequest(ctx, p.client, "POST", p.baseURL+":generateContent", headers, payload)
+	if err != nil {
+		return nil, requestError("Google Vertex AI", fmt.Errorf("failed to send request to vertex ai: %w", err))
 	}
 
 	if statusCode != http.StatusOK {
-		if apiResp.Error != nil {
-			return "", fmt.Errorf("vertex ai api error: %s", apiResp.Error.Message)
+		return nil, p.errorFromResponse(statusCode, respBody)
+	}
+
+	var apiResp geminiResponse
+	if err := json.Unmarshal(respBody, &apiResp); err != nil {
+		return nil, fmt.Errorf("failed to parse vertex ai json response: %w", err)
+	}
+
+	if len(apiResp.Candidates) == 0 {
+		if err := geminiFinishError("Google Vertex AI", "", nil, apiResp.PromptFeedback, nil); err != nil {
+			return nil, err
 		}
-		return "", fmt.Errorf("received non-200 status from vertex ai: %d", statusCode)
+		return nil, fmt.Errorf("received an empty or invalid response from vertex ai")
+	}
+
+	candidate := apiResp.Candidates[0]
+	var text, thoughts strings.Builder
+	for _, part := range candidate.Content.Parts {
+		if part.Thought {
+			thoughts.WriteString(part.Text)
+			continue
+		}
+		text.WriteString(part.Text)
+	}
+
+	result := newReasoningResponse(text.String(), thoughts.String(), apiResp.UsageMetadata.toUsage(), thinkTagsFor(p.model, false))
+	if err := geminiFinishError("Google Vertex AI", candidate.FinishReason, candidate.SafetyRatings, apiResp.PromptFeedback, result); err != nil {
+		return nil, err
+	}
+	if result.Text == "" {
+		return nil, fmt.Errorf("received an empty or invalid response from vertex ai")
+	}
+	return result, nil
+}
+
+func (p *VertexAIProvider) GenerateStream(ctx context.Context, req Request, onChunk StreamHandler) (*Response, error) {
+	payload, headers, err := p.newRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := doStreamRequest(ctx, p.client, "POST", p.baseURL+":streamGenerateContent?alt=sse", headers, payload)
+	if err != nil {
+		return nil, requestError("Google Vertex AI", fmt.Errorf("failed to send request to vertex ai: %w", err))
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, p.errorFromResponse(resp.StatusCode, respBody)
 	}
 
-	if len(apiResp.Candidates) == 0 || len(apiResp.Candidates[0].Content.Parts) == 0 {
-		return "", fmt.Errorf("received an empty or invalid response from vertex ai")
+	stream := newReasoningStream(ctx, onChunk, thinkTagsFor(p.model, false))
+	var usage Usage
+	var finishReason string
+	var ratings []geminiSafetyRating
+	var feedback *geminiPromptFeedback
+	err = readSSE(resp.Body, func(_, data string) error {
+		var chunk geminiResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("failed to parse vertex ai stream chunk: %w", err)
+		}
+		if chunk.Error != nil {
+			return newAPIError("Google Vertex AI", p.model, chunk.Error.Code, nil, fmt.Sprintf("vertex ai api error: %s", chunk.Error.Message))
+		}
+		if chunk.UsageMetadata != nil {
+			usage = chunk.UsageMetadata.toUsage()
+		}
+		if chunk.PromptFeedback != nil {
+			feedback = chunk.PromptFeedback
+		}
+		if len(chunk.Candidates) == 0 {
+			return nil
+		}
+		if reason := chunk.Candidates[0].FinishReason; reason != "" {
+			finishReason, ratings = reason, chunk.Candidates[0].SafetyRatings
+		}
+		for _, part := range chunk.Candidates[0].Content.Parts {
+			if part.Thought {
+				stream.Reasoning(part.Text)
+				continue
+			}
+			stream.Text(part.Text)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, requestError("Google Vertex AI", fmt.Errorf("vertex ai stream failed: %w", err))
 	}
 
-	return apiResp.Candidates[0].Content.Parts[0].Text, nil
+	result := stream.Response(usage)
+	if err := geminiFinishError("Google Vertex AI", finishReason, ratings, feedback, result); err != nil {
+		return nil, err
+	}
+	if result.Text == "" {
+		return nil, fmt.Errorf("received an empty or invalid response from vertex ai")
+	}
+	return result, nil
 }
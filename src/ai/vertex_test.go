@@ -0,0 +1,46 @@
+package ai
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVertexCredentialFailuresAreAuthErrors(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", "")
+
+	if _, err := NewVertexAIProvider("", "", "", "gemini-2.5-flash", "project", "us-central1", nil, nil); !errors.Is(err, ErrAuth) {
+		t.Errorf("no credentials: got %v, want ErrAuth", err)
+	}
+
+	bad := filepath.Join(dir, "creds.json")
+	if err := os.WriteFile(bad, []byte(`{"type": "service_account"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewVertexAIProvider("", bad, "", "gemini-2.5-flash", "project", "us-central1", nil, nil); !errors.Is(err, ErrAuth) {
+		t.Errorf("incomplete credentials file: got %v, want ErrAuth", err)
+	}
+}
+
+func TestVertexSharesTheGeminiRequest(t *testing.T) {
+	provider, err := NewVertexAIProvider("token", "", "", "gemini-2.5-flash", "project", "us-central1", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, _, err := provider.newRequest(t.Context(), Request{Messages: UserPrompt("hi")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), `"generationConfig":{"maxOutputTokens":`) {
+		t.Errorf("vertex request should always carry a token limit, got %s", body)
+	}
+}
@@ -1,7 +1,11 @@
 package commands
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"gct/src/ai"
 	"gct/src/config"
 	"os/exec"
 	"strings"
@@ -9,35 +13,45 @@ import (
 	"github.com/fatih/color"
 )
 
-const aiEditCommitPromptTemplate = `
-You are a helpful assistant revising a git commit message based on user feedback.
-The user has provided an original commit message and an instruction on how to change it.
+const commitJSONInstructions = `
+Respond with a single JSON object and nothing else, using exactly these fields:
+- "type": the commit type, spelled the way the guidelines spell it (e.g. "Feat" or "✨ Feat")
+- "scope": the part of the code that changed, or "" if the guidelines do not use scopes
+- "subject": the short summary line, without the type or scope
+- "body": the detailed explanation of the change, or "" if none is needed
+- "breaking": true only if the change breaks backwards compatibility
+- "footers": trailers such as issue references or co-authors, as {"token": "...", "value": "..."} objects, or [] if none
+`
 
-Here is the original commit message:
---- ORIGINAL MESSAGE START ---
-%s
---- ORIGINAL MESSAGE END ---
+const aiEditCommitPromptTemplate = `
+Please revise the commit message you just wrote based on my feedback.
 
-Here is the user's instruction for the change:
+Here is my instruction for the change:
 --- USER INSTRUCTION START ---
 %s
 --- USER INSTRUCTION END ---
 
-Your task is to generate the full, revised commit message that incorporates the user's instruction.
-Maintain the original conventional commit format (e.g. "Type: Subject").
-ONLY output the raw, complete, revised commit message. Do not add any extra commentary.
-`
+Keep using the guidelines and the staged changes from earlier in this conversation.
+Reply with the complete, revised commit message as the same JSON object.
+` + commitJSONInstructions
 
-const aiCommitPromptTemplateWithContext = `
+const aiRepairCommitPromptTemplate = `
+Your last reply could not be used: %v.
+Reply again with only the corrected JSON object.
+` + commitJSONInstructions
+
+const aiCommitSystemPromptTemplate = `
 You are an expert programmer creating a commit message.
-Your task is to generate a concise, conventional commit message based on the provided guidelines, staged code changes, and any additional context.
+Your task is to generate a concise, conventional commit message based on the provided guidelines, the staged code changes, and any additional context from the user.
 
 Adhere strictly to the following guidelines:
 --- GUIDELINES START ---
 %s
 --- GUIDELINES END ---
+` + commitJSONInstructions
 
-Here is the additional context provided by the user. Incorporate this information into the commit message body where appropriate (e.g. for co-authorship, issue numbers, or specific explanations):
+const aiCommitPromptTemplateWithContext = `
+Here is the additional context provided by the user. Incorporate this information into the commit message body or footers where appropriate (e.g. for co-authorship, issue numbers, or specific explanations):
 --- ADDITIONAL CONTEXT START ---
 %s
 --- ADDITIONAL CONTEXT END ---
@@ -48,29 +62,167 @@ Here are the staged changes (git diff):
 --- GIT DIFF END ---
 
 Based on all the information above, generate the complete commit message.
-The message must have a subject line, a blank line, and then the body.
-ONLY output the raw commit message itself, without any extra commentary, introductory text, or markdown formatting like backticks.
 `
 
 const aiCommitPromptTemplate = `
-You are an expert programmer creating a commit message.
-Your task is to generate a concise, conventional commit message based on the provided guidelines and staged code changes.
-
-Adhere strictly to the following guidelines:
---- GUIDELINES START ---
-%s
---- GUIDELINES END ---
-
 Here are the staged changes (git diff):
 --- GIT DIFF START ---
 %s
 --- GIT DIFF END ---
 
 Based on the guidelines and the diff, generate the complete commit message.
-The message must have a subject line, a blank line, and then the body.
-ONLY output the raw commit message itself, without any extra commentary, introductory text, or markdown formatting like backticks.
 `
 
+const maxCommitRepairAttempts = 2
+
+type commitFooter struct {
+	Token string `json:"token"`
+	Value string `json:"value"`
+}
+
+type commitMessage struct {
+	Type     string         `json:"type"`
+	Scope    string         `json:"scope"`
+	Subject  string         `json:"subject"`
+	Body     string         `json:"body"`
+	Breaking bool           `json:"breaking"`
+	Footers  []commitFooter `json:"footers"`
+}
+
+var commitMessageSchema = &ai.ResponseSchema{
+	Name: "commit_message",
+	Schema: map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"type":     map[string]interface{}{"type": "string"},
+			"scope":    map[string]interface{}{"type": "string"},
+			"subject":  map[string]interface{}{"type": "string"},
+			"body":     map[string]interface{}{"type": "string"},
+			"breaking": map[string]interface{}{"type": "boolean"},
+			"footers": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"token": map[string]interface{}{"type": "string"},
+						"value": map[string]interface{}{"type": "string"},
+					},
+					"required":             []string{"token", "value"},
+					"additionalProperties": false,
+				},
+			},
+		},
+		"required":             []string{"type", "scope", "subject", "body", "breaking", "footers"},
+		"additionalProperties": false,
+	},
+}
+
+func decodeCommitMessage(text string) (commitMessage, error) {
+	var msg commitMessage
+	if err := commitMessageSchema.Decode(text, &msg); err != nil {
+		return msg, err
+	}
+
+	msg.Type = strings.TrimSpace(msg.Type)
+	msg.Scope = strings.TrimSpace(msg.Scope)
+	msg.Subject = strings.TrimSpace(msg.Subject)
+	msg.Body = strings.TrimSpace(msg.Body)
+
+	switch {
+	case msg.Type == "":
+		return msg, fmt.Errorf("\"type\" is empty")
+	case msg.Subject == "":
+		return msg, fmt.Errorf("\"subject\" is empty")
+	case strings.ContainsAny(msg.Type+msg.Scope+msg.Subject, "\r\n"):
+		return msg, fmt.Errorf("\"type\", \"scope\" and \"subject\" must each be a single line")
+	}
+	for _, footer := range msg.Footers {
+		if strings.TrimSpace(footer.Token) == "" || strings.TrimSpace(footer.Value) == "" {
+			return msg, fmt.Errorf("every footer needs a \"token\" and a \"value\"")
+		}
+	}
+	return msg, nil
+}
+
+func (m commitMessage) typeField() string {
+	typeField := m.Type
+	if m.Scope != "" {
+		typeField += "(" + m.Scope + ")"
+	}
+	if m.Breaking {
+		typeField += "!"
+	}
+	return typeField
+}
+
+func (m commitMessage) subjectLine() string {
+	return fmt.Sprintf("%s: %s", m.typeField(), m.Subject)
+}
+
+func (m commitMessage) fullBody() string {
+	var footers []string
+	for _, footer := range m.Footers {
+		footers = append(footers, fmt.Sprintf("%s: %s", strings.TrimSpace(footer.Token), strings.TrimSpace(footer.Value)))
+	}
+
+	parts := []string{}
+	if m.Body != "" {
+		parts = append(parts, m.Body)
+	}
+	if len(footers) > 0 {
+		parts = append(parts, strings.Join(footers, "\n"))
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+func (m commitMessage) String() string {
+	if body := m.fullBody(); body != "" {
+		return m.subjectLine() + "\n\n" + body
+	}
+	return m.subjectLine()
+}
+
+func (m commitMessage) asReply() ai.Message {
+	data, _ := json.Marshal(m)
+	return ai.Message{Role: ai.RoleAssistant, Content: string(data)}
+}
+
+func runCommitConversation(messages []ai.Message) (string, error) {
+	task, err := prepareAITask("commit", messages, true)
+	if err != nil {
+		return "", err
+	}
+	if !task.fromCache {
+		task.showProgress()
+	}
+	task.schema = commitMessageSchema
+	return task.run(context.Background(), nil)
+}
+
+func ensureValidCommitMessage(conversation []ai.Message, reply string) (commitMessage, error) {
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	msg, err := decodeCommitMessage(reply)
+	for attempt := 1; err != nil; attempt++ {
+		if attempt > maxCommitRepairAttempts {
+			return msg, fmt.Errorf("the AI did not return a valid commit message: %w", err)
+		}
+		fmt.Printf("%s Invalid commit message (%v), asking the AI to fix it (%d/%d)...\n",
+			yellow("⟳"), err, attempt, maxCommitRepairAttempts)
+
+		conversation = append(conversation,
+			ai.Message{Role: ai.RoleAssistant, Content: reply},
+			ai.Message{Role: ai.RoleUser, Content: fmt.Sprintf(aiRepairCommitPromptTemplate, err)},
+		)
+		reply, err = runCommitConversation(conversation)
+		if err != nil {
+			return msg, err
+		}
+		msg, err = decodeCommitMessage(reply)
+	}
+	return msg, nil
+}
+
 func AICommitCommand(additionalContext string) {
 	cyan := color.New(color.FgCyan).SprintFunc()
 	yellow := color.New(color.FgYellow).SprintFunc()
@@ -99,30 +251,65 @@ func AICommitCommand(additionalContext string) {
 		return
 	}
 
-	var prompt string
 	if additionalContext != "" {
 		fmt.Println(cyan("✍️ Applying additional user context..."))
-		prompt = fmt.Sprintf(aiCommitPromptTemplateWithContext, guidelines, additionalContext, string(diffOutput))
-	} else {
-		prompt = fmt.Sprintf(aiCommitPromptTemplate, guidelines, string(diffOutput))
+	}
+	buildConversation := func(diff string) []ai.Message {
+		prompt := fmt.Sprintf(aiCommitPromptTemplate, diff)
+		if additionalContext != "" {
+			prompt = fmt.Sprintf(aiCommitPromptTemplateWithContext, additionalContext, diff)
+		}
+		return ai.SystemPrompt(fmt.Sprintf(aiCommitSystemPromptTemplate, guidelines), prompt)
 	}
 
-	initialGeneratedMsg, err := runAITask(prompt, false)
+	diff := fitDiffToModel("commit", string(diffOutput), diffCmd.Args[2:], buildConversation, false)
+	task, err := prepareAITask("commit", buildConversation(diff), false)
 	if err != nil {
-		if err.Error() == "operation cancelled by user" {
+		if errors.Is(err, ai.ErrCancelled) {
 			fmt.Println(color.YellowString("Commit cancelled."))
 		} else {
-			fmt.Printf("%s %v\n", color.RedString("Error:"), err)
+			printAIError(err)
 		}
 		return
 	}
 
-	currentMessage := strings.TrimSpace(initialGeneratedMsg)
-	currentMessage = strings.Trim(currentMessage, "`")
+	task.schema = commitMessageSchema
+	task.shrink = newDiffShrinker(diff, buildConversation)
+	stream := &commitStreamPrinter{}
+	reply, err := task.run(context.Background(), stream.write)
+	stream.finish()
+	if errors.Is(err, ai.ErrCancelled) {
+		fmt.Println(yellow("\nCommit cancelled."))
+		return
+	}
+	if err != nil {
+		printAIError(err)
+		return
+	}
+	conversation := task.messages
+	fmt.Printf("\r%s\n", green("✓ Done!                     "))
+	if task.usedFallback() {
+		fmt.Printf("%s Answered by %s\n", green("✓"), providerLabel(task.answeredBy))
+	}
+	if task.usage != (ai.Usage{}) {
+		fmt.Printf("%s  %s\n", cyan("ℹ"), usageSummary(task.usage))
+	}
+	printReasoning(task.reasoning)
+
+	currentMessage, err := ensureValidCommitMessage(conversation, reply)
+	if err != nil {
+		printAIError(err)
+		return
+	}
+	conversation = append(conversation, currentMessage.asReply())
+	alreadyShown := stream.showed(currentMessage)
 
 	for {
-		fmt.Printf("\n%s AI Generated Commit Message:\n", cyan("🤖"))
-		fmt.Printf("%s\n%s\n%s\n", yellow("--- Start ---"), green(currentMessage), yellow("--- End ---"))
+		if !alreadyShown {
+			fmt.Printf("\n%s AI Generated Commit Message:\n", cyan("🤖"))
+			fmt.Printf("%s\n%s\n%s\n", yellow("--- Start ---"), green(currentMessage.String()), yellow("--- End ---"))
+		}
+		alreadyShown = false
 
 		action := promptForAction("Press [c] to chat/change, [e] to edit, [Enter] to commit, [q] to quit:")
 
@@ -134,28 +321,36 @@ func AICommitCommand(additionalContext string) {
 				continue
 			}
 
-			revisionPrompt := fmt.Sprintf(aiEditCommitPromptTemplate, currentMessage, changeRequest)
+			revisionRequest := ai.Message{
+				Role:    ai.RoleUser,
+				Content: fmt.Sprintf(aiEditCommitPromptTemplate, changeRequest),
+			}
+
+			revisionConversation := append(conversation, revisionRequest)
+			revisedReply, err := runCommitConversation(revisionConversation)
+			if err != nil {
+				printAIError(err)
+				continue
+			}
 
-			revisedMsg, err := runAITask(revisionPrompt, true)
+			revisedMsg, err := ensureValidCommitMessage(revisionConversation, revisedReply)
 			if err != nil {
-				fmt.Printf("%s %v\n", red("Error:"), err)
+				printAIError(err)
 				continue
 			}
 
-			currentMessage = strings.TrimSpace(revisedMsg)
-			currentMessage = strings.Trim(currentMessage, "`")
+			currentMessage = revisedMsg
+			conversation = append(revisionConversation, currentMessage.asReply())
 			continue
 
 		case 'e':
 			fmt.Println(cyan("\n✍️ Opening editor..."))
-			cType, cSubject, body := parseCommitMessage(currentMessage)
-			tuiModel := NewCommitTUIModel(cType, cSubject, body)
+			tuiModel := NewCommitTUIModel(currentMessage.typeField(), currentMessage.Subject, currentMessage.fullBody())
 			runCommitTUI(tuiModel, false)
 			return
 
 		case '\n':
-			subjectLine, body, _ := strings.Cut(currentMessage, "\n\n")
-			err := executeGitCommit(subjectLine, body, false)
+			err := executeGitCommit(currentMessage.subjectLine(), currentMessage.fullBody(), false)
 			if err != nil {
 				return
 			}
@@ -1,12 +1,12 @@
 package commands
 
 import (
+	"errors"
 	"fmt"
+	"gct/src/ai"
 	"os"
 	"os/exec"
-	"strings"
 
-	tea "github.com/charmbracelet/bubbletea"
 	"github.com/fatih/color"
 )
 
@@ -66,23 +66,24 @@ func AIDiffCommand() {
 		return
 	}
 
-	prompt := fmt.Sprintf(aiDiffPromptTemplate, string(diffOutput))
-	aiResponse, err := runAITask(prompt, false)
+	buildPrompt := func(diff string) []ai.Message {
+		return ai.UserPrompt(fmt.Sprintf(aiDiffPromptTemplate, diff))
+	}
+	ctx, cancel := commandContext("diff")
+	defer cancel()
+	diff, summarized, err := summarizeLargeDiff(ctx, "diff", string(diffOutput), diffCmd.Args[2:], buildPrompt, false)
+	if err == nil {
+		var shrink diffShrinker
+		if !summarized {
+			shrink = newDiffShrinker(diff, buildPrompt)
+		}
+		err = runAITaskInViewer(ctx, "diff", "🤖 AI Explanation of Changes", buildPrompt(diff), shrink)
+	}
 	if err != nil {
-		if err.Error() == "operation cancelled by user" {
+		if errors.Is(err, ai.ErrCancelled) {
 			fmt.Println(color.YellowString("Diff analysis cancelled."))
 		} else {
-			fmt.Printf("%s %v\n", color.RedString("Error:"), err)
+			printAIError(err)
 		}
-		return
-	}
-
-	cleanMsg := strings.TrimSpace(aiResponse)
-
-	viewerModel := NewAITextViewerModel("🤖 AI Explanation of Changes", cleanMsg)
-	p := tea.NewProgram(viewerModel, tea.WithAltScreen())
-
-	if _, err := p.Run(); err != nil {
-		fmt.Printf("%s Error displaying AI response: %v\n", color.RedString("Error:"), err)
 	}
 }
@@ -2,83 +2,576 @@ package commands
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"gct/src/ai"
 	"gct/src/config"
+	"math"
+	"net/http"
 	"os"
+	"os/signal"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
+	tea "github.com/charmbracelet/bubbletea"
 	"github.com/fatih/color"
 )
 
 var NoCache bool
 
-const tokenWarningThreshold = 4000
-const charsPerToken = 4
+var ShowReasoning bool
 
-func runAITask(prompt string, isSilent bool) (string, error) {
+const contextWarningRatio = 0.8
+
+const truncatedRetryMaxTokens = 16384
+
+type aiTask struct {
+	command    string
+	cfg        *config.Config
+	messages   []ai.Message
+	cacheKey   string
+	cached     string
+	fromCache  bool
+	answeredBy config.ProviderConfig
+	fellBack   bool
+	usage      ai.Usage
+	reasoning  string
+	warning    string
+	schema     *ai.ResponseSchema
+	onRetry    ai.RetryNotifier
+	onNotice   func(message string)
+	onFallback func(from, to config.ProviderConfig, reason error)
+	onAttempt  func(candidate config.ProviderConfig)
+	shrink     diffShrinker
+	spinner    *progressSpinner
+	providers  *providerPool
+}
+
+type providerPool struct {
+	mu        sync.Mutex
+	providers map[int]pooledProvider
+}
+
+type pooledProvider struct {
+	provider ai.AIProvider
+	err      error
+}
+
+func newProviderPool() *providerPool {
+	return &providerPool{providers: map[int]pooledProvider{}}
+}
+
+func (p *providerPool) get(cfg *config.Config, index int, candidate config.ProviderConfig) (ai.AIProvider, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if pooled, ok := p.providers[index]; ok {
+		return pooled.provider, pooled.err
+	}
+	provider, err := ai.NewProvider(cfg.WithProvider(candidate))
+	p.providers[index] = pooledProvider{provider: provider, err: err}
+	return provider, err
+}
+
+func fallbackStatus(from, to config.ProviderConfig, reason error) string {
+	return fmt.Sprintf("%s failed (%v), falling back to %s", from.Provider, reason, providerLabel(to))
+}
+
+func retryStatus(event ai.RetryEvent) string {
+	return fmt.Sprintf("%v, retrying in %ds (attempt %d/%d)",
+		event.Reason, int(math.Ceil(event.Delay.Seconds())), event.Attempt, event.MaxAttempts)
+}
+
+func messagesCacheInput(messages []ai.Message) string {
+	if len(messages) == 1 && messages[0].Role == ai.RoleUser {
+		return messages[0].Content
+	}
+
+	var sb strings.Builder
+	for _, msg := range messages {
+		sb.WriteString(string(msg.Role))
+		sb.WriteString("\x00")
+		sb.WriteString(msg.Content)
+		sb.WriteString("\x1e")
+	}
+	return sb.String()
+}
+
+func usageSummary(usage ai.Usage) string {
+	input := usage.InputTokens + usage.CacheReadTokens + usage.CacheWriteTokens
+	summary := fmt.Sprintf("Tokens used: %s input", formatTokenCount(input))
+	if usage.CacheReadTokens > 0 || usage.CacheWriteTokens > 0 {
+		summary += fmt.Sprintf(" (%s read from cache, %s written to cache)",
+			formatTokenCount(usage.CacheReadTokens), formatTokenCount(usage.CacheWriteTokens))
+	}
+	return summary + fmt.Sprintf(", %s output", formatTokenCount(usage.OutputTokens))
+}
+
+func printReasoning(reasoning string) {
+	faint := color.New(color.Faint).SprintFunc()
+	magenta := color.New(color.FgMagenta).SprintFunc()
+
+	if !ShowReasoning || reasoning == "" {
+		return
+	}
+	fmt.Printf("%s\n%s\n", magenta("💭 Reasoning:"), faint(strings.TrimSpace(reasoning)))
+}
+
+func prepareAITask(command string, messages []ai.Message, isSilent bool) (*aiTask, error) {
 	cyan := color.New(color.FgCyan).SprintFunc()
 	yellow := color.New(color.FgYellow).SprintFunc()
-	green := color.New(color.FgGreen).SprintFunc()
 	magenta := color.New(color.FgMagenta).SprintFunc()
 
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		return "", fmt.Errorf("failed to load configuration: %w", err)
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
 	}
 
-	cacheKey := getCacheKey(prompt)
-
-	if cfg.Cache.Enabled && !NoCache {
-		if content, found := readFromCache(cacheKey); found {
-			if !isSilent {
-				fmt.Printf("%s Using cached response. Use --no-cache to regenerate.\n", cyan("✓"))
-			}
-			return content, nil
+	task := newAITask(command, cfg, messages)
+	if task.fromCache {
+		if !isSilent {
+			fmt.Printf("%s Using cached response. Use --no-cache to regenerate.\n", cyan("✓"))
 		}
+		return task, nil
 	}
 
 	if !isSilent {
-		estimatedTokens := len(prompt) / charsPerToken
-		fmt.Printf("%s  Estimated tokens: ~%d\n", cyan("ℹ"), estimatedTokens)
-
-		if estimatedTokens > tokenWarningThreshold {
-			warningMsg := fmt.Sprintf(
-				"The input is large (~%d tokens). This may result in a long wait or higher costs.",
-				estimatedTokens,
-			)
+		task.showProgress()
+
+		count := ai.CountTokens(cfg.ProviderConfig, messages)
+		window := ai.LookupModel(cfg, cfg.ProviderConfig).ContextWindow
+		fmt.Printf("%s  %s\n", cyan("ℹ"), describeTokenCount(count, window))
+
+		if warningMsg := contextWarning(cfg.ProviderConfig, count, window); warningMsg != "" {
 			fmt.Printf("%s %s\n", yellow("Warning:"), warningMsg)
 			if !confirmPrompt("Do you want to continue?") {
-				return "", fmt.Errorf("operation cancelled by user")
+				return nil, fmt.Errorf("%w by user", ai.ErrCancelled)
 			}
 		}
 		fmt.Printf("%s Using %s from %s\n", cyan("›"), magenta(cfg.Model), magenta(cfg.Provider))
-		fmt.Println(cyan("[Thinking]..."))
 	}
 
-	provider, err := ai.NewProvider(cfg)
+	return task, nil
+}
+
+func (t *aiTask) showProgress() {
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	t.spinner = newProgressSpinner()
+	t.onRetry = func(event ai.RetryEvent) {
+		t.spinner.Println(fmt.Sprintf("%s %s", yellow("⟳"), retryStatus(event)))
+	}
+	t.onFallback = func(from, to config.ProviderConfig, reason error) {
+		t.spinner.Println(fmt.Sprintf("%s %s", yellow("↪"), fallbackStatus(from, to, reason)))
+	}
+	t.onNotice = func(message string) {
+		t.spinner.Println(fmt.Sprintf("%s %s", yellow("⟳"), message))
+	}
+	t.onAttempt = func(candidate config.ProviderConfig) {
+		t.spinner.Start(providerLabel(candidate))
+	}
+}
+
+func newAITask(command string, cfg *config.Config, messages []ai.Message) *aiTask {
+	task := &aiTask{
+		command:   command,
+		cfg:       cfg,
+		providers: newProviderPool(),
+	}
+	task.setMessages(messages)
+	return task
+}
+
+func (t *aiTask) setMessages(messages []ai.Message) {
+	t.messages = messages
+	t.cacheKey = getCacheKey(messagesCacheInput(messages))
+	t.cached, t.fromCache = "", false
+	if t.cfg.Cache.Enabled && !NoCache {
+		t.cached, t.fromCache = readFromCache(t.cacheKey)
+	}
+}
+
+func (t *aiTask) withMessages(messages []ai.Message) *aiTask {
+	clone := &aiTask{
+		command:    t.command,
+		cfg:        t.cfg,
+		schema:     t.schema,
+		onRetry:    t.onRetry,
+		onNotice:   t.onNotice,
+		onFallback: t.onFallback,
+		onAttempt:  t.onAttempt,
+		providers:  t.providers,
+	}
+	clone.setMessages(messages)
+	return clone
+}
+
+func formatPromptTokens(count ai.TokenCount) string {
+	if count.Exact {
+		return formatTokenCount(count.Tokens) + " tokens"
+	}
+	return "~" + formatTokenCount(count.Tokens) + " tokens"
+}
+
+func describeTokenCount(count ai.TokenCount, window int) string {
+	description := "Estimated prompt size: " + formatPromptTokens(count)
+	if count.Exact {
+		description = fmt.Sprintf("Prompt size: %s (%s)", formatPromptTokens(count), count.Encoding)
+	}
+	if window > 0 {
+		description += fmt.Sprintf(", %d%% of the %s context window", count.Tokens*100/window, formatContextWindow(window))
+	}
+	return description
+}
+
+func contextWarning(provider config.ProviderConfig, count ai.TokenCount, window int) string {
+	switch {
+	case window <= 0:
+		return ""
+	case count.Tokens > window:
+		return fmt.Sprintf("The input (%s) is larger than the %s context window of %s. The request will likely be rejected.",
+			formatPromptTokens(count), formatContextWindow(window), providerLabel(provider))
+	case float64(count.Tokens) > float64(window)*contextWarningRatio:
+		return fmt.Sprintf("The input (%s) fills most of the %s context window of %s, leaving little room for the answer.",
+			formatPromptTokens(count), formatContextWindow(window), providerLabel(provider))
+	}
+	return ""
+}
+
+func providerLabel(provider config.ProviderConfig) string {
+	return fmt.Sprintf("%s (%s)", provider.Provider, provider.Model)
+}
+
+func (t *aiTask) run(ctx context.Context, onChunk ai.StreamHandler) (string, error) {
+	if t.fromCache {
+		if onChunk != nil {
+			onChunk(t.cached)
+		}
+		return t.cached, nil
+	}
+
+	ctx, cancel := t.context(ctx)
+	defer cancel()
+	if t.spinner != nil {
+		defer t.spinner.Stop()
+	}
+
+	ctx = ai.WithRetryPolicy(ctx, ai.NewRetryPolicy(t.cfg.Retry))
+	if t.onRetry != nil {
+		ctx = ai.WithRetryNotifier(ctx, t.onRetry)
+	}
+
+	req := ai.Request{
+		Messages: t.messages,
+		Options:  ai.NewGenerationOptions(t.cfg.Generation.ForCommand(t.command)),
+	}
+	req.Options.Schema = t.schema
+	if effort := req.Options.ReasoningEffort; effort != "" && !ai.IsReasoningEffort(effort) {
+		return "", fmt.Errorf("invalid reasoning_effort '%s': use minimal, low, medium or high", effort)
+	}
+
+	chain := t.cfg.ProviderChain()
+	var generatedText string
+	var lastErr error
+
+	for i, candidate := range chain {
+		if i > 0 && t.onFallback != nil {
+			t.onFallback(chain[i-1], candidate, lastErr)
+		}
+		if t.onAttempt != nil {
+			t.onAttempt(candidate)
+		}
+
+		provider, err := t.providers.get(t.cfg, i, candidate)
+		if err != nil {
+			lastErr = fmt.Errorf("failed to initialize AI provider: %w", err)
+			continue
+		}
+
+		emitted := false
+		var handler ai.StreamHandler
+		if onChunk != nil {
+			handler = func(chunk string) {
+				if !emitted && t.spinner != nil {
+					t.spinner.Stop()
+				}
+				emitted = true
+				onChunk(chunk)
+			}
+		}
+
+		resp, err := generate(ctx, provider, req, handler)
+		for errors.Is(err, ai.ErrContextTooLong) && !emitted && t.shrink != nil {
+			messages, ok := t.shrink()
+			if !ok {
+				break
+			}
+			if t.onNotice != nil {
+				t.onNotice(fmt.Sprintf("The input is too long for %s, retrying with a reduced diff (%s)",
+					providerLabel(candidate), formatPromptTokens(ai.CountTokens(candidate, messages))))
+			}
+			t.messages = messages
+			req.Messages = messages
+			resp, err = generate(ctx, provider, req, handler)
+		}
+		var truncated *ai.TruncatedError
+		if errors.As(err, &truncated) && !emitted {
+			retry := req
+			retry.Options.MaxTokens = truncatedRetryTokens(req.Options.MaxTokens)
+			if t.onNotice != nil {
+				t.onNotice(fmt.Sprintf("%v, retrying with max_tokens %d", err, retry.Options.MaxTokens))
+			}
+			partial := truncated
+			resp, err = generate(ctx, provider, retry, handler)
+			if err != nil && !errors.As(err, &truncated) && !emitted && !errors.Is(err, ai.ErrCancelled) && !errors.Is(err, context.Canceled) {
+				if t.onNotice != nil {
+					t.onNotice(fmt.Sprintf("The retry with max_tokens %d failed: %v", retry.Options.MaxTokens, err))
+				}
+				err = partial
+			}
+		}
+		if errors.As(err, &truncated) && truncated.Response != nil && truncated.Response.Text != "" && t.schema == nil {
+			resp, err = truncated.Response, nil
+			t.warning = fmt.Sprintf("%v. The answer may be incomplete; raise generation.max_tokens to allow longer answers.", truncated)
+		}
+		if err == nil {
+			generatedText = resp.Text
+			t.answeredBy = candidate
+			t.fellBack = i > 0
+			t.usage = resp.Usage
+			t.reasoning = resp.Reasoning
+			recordUsage(t.command, candidate, resp.Usage)
+			lastErr = nil
+			break
+		}
+
+		lastErr = fmt.Errorf("AI generation failed: %w", err)
+		blocked := t.cfg.FallbackOnBlocked && errors.Is(err, ai.ErrContentBlocked)
+		if emitted || ctx.Err() != nil || !(ai.ShouldFallback(err) || blocked) {
+			break
+		}
+	}
+
+	if lastErr != nil {
+		switch {
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			return "", t.deadlineError()
+		case errors.Is(ctx.Err(), context.Canceled):
+			return "", fmt.Errorf("%w by user", ai.ErrCancelled)
+		}
+		return "", lastErr
+	}
+
+	if t.cfg.Cache.Enabled && t.matchesSchema(generatedText) {
+		writeToCache(t.cacheKey, generatedText)
+	}
+
+	return generatedText, nil
+}
+
+func (t *aiTask) context(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, stop := interruptContext(parent)
+	if _, ok := parent.Deadline(); ok {
+		return ctx, stop
+	}
+	return withDeadline(ctx, stop, t.cfg.Deadline.ForCommand(t.command))
+}
+
+func (t *aiTask) deadlineError() error {
+	return fmt.Errorf("%w: no answer within %s; raise 'deadline.%s' or 'deadline.default' in gct.yaml",
+		ai.ErrTimeout, t.cfg.Deadline.ForCommand(t.command), t.command)
+}
+
+func commandContext(command string) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cfg, err := config.LoadConfig()
 	if err != nil {
-		return "", fmt.Errorf("failed to initialize AI provider: %w", err)
+		return ctx, cancel
 	}
+	return withDeadline(ctx, cancel, cfg.Deadline.ForCommand(command))
+}
 
-	ctx := context.Background()
-	generatedText, err := provider.Generate(ctx, prompt)
+func withDeadline(ctx context.Context, stop context.CancelFunc, deadline time.Duration) (context.Context, context.CancelFunc) {
+	if deadline <= 0 {
+		return ctx, stop
+	}
+	ctx, cancel := context.WithTimeout(ctx, deadline)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
 
-	if cfg.Cache.Enabled {
-		writeToCache(cacheKey, generatedText)
+func interruptContext(parent context.Context) (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(parent, os.Interrupt, syscall.SIGTERM)
+}
+
+func generate(ctx context.Context, provider ai.AIProvider, req ai.Request, handler ai.StreamHandler) (*ai.Response, error) {
+	if handler != nil {
+		return provider.GenerateStream(ctx, req, handler)
+	}
+	return provider.Generate(ctx, req)
+}
+
+func truncatedRetryTokens(maxTokens int) int {
+	if maxTokens <= 0 {
+		return truncatedRetryMaxTokens
+	}
+	return maxTokens * 2
+}
+
+func (t *aiTask) matchesSchema(text string) bool {
+	if t.schema == nil {
+		return true
 	}
+	var decoded interface{}
+	return t.schema.Decode(text, &decoded) == nil
+}
+
+func (t *aiTask) usedFallback() bool {
+	return !t.fromCache && t.fellBack
+}
+
+func runAIConversation(ctx context.Context, command string, messages []ai.Message, shrink diffShrinker, isSilent bool, onChunk ai.StreamHandler) (string, error) {
+	green := color.New(color.FgGreen).SprintFunc()
+	cyan := color.New(color.FgCyan).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	task, err := prepareAITask(command, messages, isSilent)
+	if err != nil {
+		return "", err
+	}
+	task.shrink = shrink
+
+	generatedText, err := task.run(ctx, onChunk)
 
 	if !isSilent {
 		fmt.Printf("\r%s\n", green("✓ Done!                     "))
+		if err == nil && task.usedFallback() {
+			fmt.Printf("%s Answered by %s\n", green("✓"), providerLabel(task.answeredBy))
+		}
+		if err == nil && task.usage != (ai.Usage{}) {
+			fmt.Printf("%s  %s\n", cyan("ℹ"), usageSummary(task.usage))
+		}
+		if err == nil {
+			printReasoning(task.reasoning)
+		}
+		if err == nil && task.warning != "" {
+			fmt.Printf("%s %s\n", yellow("Warning:"), task.warning)
+		}
 	}
 
 	if err != nil {
-		return "", fmt.Errorf("AI generation failed: %w", err)
+		return "", err
 	}
 
 	return generatedText, nil
 }
 
+func runAITaskInViewer(parent context.Context, command, title string, messages []ai.Message, shrink diffShrinker) error {
+	task, err := prepareAITask(command, messages, false)
+	if err != nil {
+		return err
+	}
+	task.shrink = shrink
+
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	viewerModel := NewStreamingAITextViewerModel(title)
+	p := tea.NewProgram(viewerModel, tea.WithAltScreen())
+	task.onRetry = func(event ai.RetryEvent) {
+		p.Send(aiStreamStatusMsg(retryStatus(event)))
+	}
+	task.onFallback = func(from, to config.ProviderConfig, reason error) {
+		p.Send(aiStreamStatusMsg(fallbackStatus(from, to, reason)))
+	}
+	task.onNotice = func(message string) {
+		p.Send(aiStreamStatusMsg(message))
+	}
+	task.onAttempt = func(candidate config.ProviderConfig) {
+		p.Send(aiStreamProviderMsg(providerLabel(candidate)))
+	}
+	task.spinner = nil
+
+	if ShowReasoning {
+		ctx = ai.WithReasoningHandler(ctx, func(chunk string) {
+			p.Send(aiStreamReasoningMsg(chunk))
+		})
+	}
+
+	genDone := make(chan error, 1)
+	go func() {
+		_, err := task.run(ctx, func(chunk string) {
+			p.Send(aiStreamChunkMsg(chunk))
+		})
+		done := aiStreamDoneMsg{err: err}
+		if err == nil && task.usage != (ai.Usage{}) {
+			done.note = usageSummary(task.usage)
+			if task.usedFallback() {
+				done.note = "Answered by " + providerLabel(task.answeredBy) + " · " + done.note
+			}
+		}
+		if err == nil && task.warning != "" {
+			done.note = strings.TrimSuffix("Warning: "+task.warning+" · "+done.note, " · ")
+		}
+		p.Send(done)
+		genDone <- err
+	}()
+
+	if _, err := p.Run(); err != nil {
+		return fmt.Errorf("error displaying AI response: %w", err)
+	}
+
+	cancel()
+	if err := <-genDone; err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, ai.ErrCancelled) {
+		return err
+	}
+	return nil
+}
+
+func printAIError(err error) {
+	red := color.New(color.FgRed).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	if errors.Is(err, ai.ErrCancelled) {
+		fmt.Println(yellow("Cancelled."))
+		return
+	}
+	fmt.Printf("%s %v\n", red("Error:"), err)
+	if hint := aiErrorHint(err); hint != "" {
+		fmt.Printf("%s %s\n", yellow("Hint:"), hint)
+	}
+}
+
+func aiErrorHint(err error) string {
+	switch {
+	case errors.Is(err, config.ErrProjectSecretCommand):
+		return "A project's gct.yaml cannot run commands. Move 'api_key_command' to your user config, or set GCT_API_KEY_COMMAND in your shell or .env."
+	case errors.Is(err, ai.ErrAuth):
+		return "The credentials are missing or were rejected. Run 'gct init' to set up your API key again."
+	case errors.Is(err, ai.ErrQuotaExhausted):
+		return "Your quota or credits are used up. Check your plan and billing, or add a fallback provider."
+	case errors.Is(err, ai.ErrRateLimited):
+		return "The provider is rate limiting requests. Wait a moment and try again, or add a fallback provider."
+	case errors.Is(err, ai.ErrModelNotFound):
+		return ai.ModelNotFoundHint
+	case errors.Is(err, ai.ErrContextTooLong):
+		return "The changes are too large for this model even after reducing the diff. Analyze a smaller range or use a model with a larger context window."
+	case errors.Is(err, ai.ErrContentBlocked):
+		return "The provider's content filter blocked the request. Check 'safety_settings' in gct.yaml."
+	case errors.Is(err, ai.ErrTimeout):
+		return "The request timed out. Try again, or raise 'http.timeout' or 'deadline' in gct.yaml."
+	}
+	var apiErr *ai.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return "The provider has no API at this address. Check 'endpoint' in gct.yaml, or the base_url of a custom provider."
+	}
+	return ""
+}
+
 func readGuidelines(paths []string) (string, error) {
 	yellow := color.New(color.FgYellow).SprintFunc()
 	var guidelines strings.Builder
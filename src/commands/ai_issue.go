@@ -2,10 +2,10 @@ package commands
 
 import (
 	"fmt"
+	"gct/src/ai"
 	"os"
 	"strings"
 
-	tea "github.com/charmbracelet/bubbletea"
 	"github.com/fatih/color"
 )
 
@@ -56,16 +56,9 @@ func AIIssueCommand() {
 	}
 
 	prompt := fmt.Sprintf(aiIssuePromptTemplate, details.Title, details.Author, strings.Join(details.Labels, ", "), details.Body)
-	aiResponse, err := runAITask(prompt, false)
-	if err != nil {
-		fmt.Printf("%s %v\n", red("Error:"), err)
-		return
-	}
-
-	cleanMsg := strings.TrimSpace(aiResponse)
-	viewerModel := NewAITextViewerModel(fmt.Sprintf("🤖 AI Proposed Solution for Issue #%s", issueNumber), cleanMsg)
-	p := tea.NewProgram(viewerModel, tea.WithAltScreen())
-	if _, err := p.Run(); err != nil {
-		fmt.Printf("%s Error displaying AI response: %v\n", red("Error:"), err)
+	ctx, cancel := commandContext("issue")
+	defer cancel()
+	if err := runAITaskInViewer(ctx, "issue", fmt.Sprintf("🤖 AI Proposed Solution for Issue #%s", issueNumber), ai.UserPrompt(prompt), nil); err != nil {
+		printAIError(err)
 	}
 }
@@ -2,17 +2,17 @@ package commands
 
 import (
 	"fmt"
+	"gct/src/ai"
 	"gct/src/config"
 	"os"
 	"os/exec"
 	"strings"
 
-	tea "github.com/charmbracelet/bubbletea"
 	"github.com/fatih/color"
 )
 
-const aiLogPromptTemplateWithGuidelines = `
-You are a release manager writing a changelog. Based on the following git diff and guidelines, generate a concise and user-friendly changelog entry.
+const aiLogSystemPromptTemplateWithGuidelines = `
+You are a release manager writing a changelog. Based on the git diff you are given and the following guidelines, generate a concise and user-friendly changelog entry.
 
 Here are the guidelines to follow:
 --- GUIDELINES START ---
@@ -24,23 +24,19 @@ Follow these rules:
 2.  **Clarity:** Write in the present tense (e.g. "Add feature" not "Added feature").
 3.  **Focus:** Emphasize user-facing changes. Ignore minor code-quality improvements or refactoring unless they have a direct impact.
 4.  **Conciseness:** Use bullet points for individual changes.
-
---- GIT DIFF START ---
-%s
---- GIT DIFF END ---
-
-Provide only the Markdown for the changelog entry below:
 `
 
-const aiLogPromptTemplate = `
-You are a release manager writing a changelog. Based on the following git diff, generate a concise and user-friendly changelog entry.
+const aiLogSystemPrompt = `
+You are a release manager writing a changelog. Based on the git diff you are given, generate a concise and user-friendly changelog entry.
 
 Follow these rules:
 1.  **Structure:** Use Markdown with headings for different categories (e.g. ### ✨ Features, ### 🐛 Bug Fixes, ### 🚀 Performance).
 2.  **Clarity:** Write in the present tense (e.g. "Add feature" not "Added feature").
 3.  **Focus:** Emphasize user-facing changes. Ignore minor code-quality improvements or refactoring unless they have a direct impact.
 4.  **Conciseness:** Use bullet points for individual changes.
+`
 
+const aiLogPromptTemplate = `
 --- GIT DIFF START ---
 %s
 --- GIT DIFF END ---
@@ -108,31 +104,39 @@ func AILogCommand() {
 
 	guidelines, _ := readGuidelines(cfg.Changelogs.Paths)
 
-	var prompt string
+	system := aiLogSystemPrompt
 	if guidelines != "" {
 		if !isCI {
 			fmt.Println(cyan("📚 Reading changelog guidelines..."))
 		}
-		prompt = fmt.Sprintf(aiLogPromptTemplateWithGuidelines, guidelines, string(diffOutput))
-	} else {
-		prompt = fmt.Sprintf(aiLogPromptTemplate, string(diffOutput))
+		system = fmt.Sprintf(aiLogSystemPromptTemplateWithGuidelines, guidelines)
 	}
-	aiResponse, err := runAITask(prompt, isCI)
+	buildMessages := func(diff string) []ai.Message {
+		return ai.SystemPrompt(system, fmt.Sprintf(aiLogPromptTemplate, diff))
+	}
+	ctx, cancel := commandContext("log")
+	defer cancel()
+	diff, summarized, err := summarizeLargeDiff(ctx, "log", string(diffOutput), diffCmd.Args[2:], buildMessages, isCI)
 	if err != nil {
-		fmt.Printf("%s %v\n", red("Error:"), err)
+		printAIError(err)
 		return
 	}
-
-	cleanMsg := strings.TrimSpace(aiResponse)
-
-	if isCI {
-		fmt.Println(cleanMsg)
-	} else {
-		viewerModel := NewAITextViewerModel("🤖 AI Generated Changelog", cleanMsg)
-		p := tea.NewProgram(viewerModel, tea.WithAltScreen())
-
-		if _, err := p.Run(); err != nil {
-			fmt.Printf("%s Error displaying AI response: %v\n", red("Error:"), err)
+	var shrink diffShrinker
+	if !summarized {
+		shrink = newDiffShrinker(diff, buildMessages)
+	}
+	if !isCI {
+		if err := runAITaskInViewer(ctx, "log", "🤖 AI Generated Changelog", buildMessages(diff), shrink); err != nil {
+			printAIError(err)
 		}
+		return
 	}
+
+	aiResponse, err := runAIConversation(ctx, "log", buildMessages(diff), shrink, isCI, nil)
+	if err != nil {
+		printAIError(err)
+		return
+	}
+
+	fmt.Println(strings.TrimSpace(aiResponse))
 }
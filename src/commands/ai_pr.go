@@ -2,10 +2,9 @@ package commands
 
 import (
 	"fmt"
+	"gct/src/ai"
 	"os"
-	"strings"
 
-	tea "github.com/charmbracelet/bubbletea"
 	"github.com/fatih/color"
 )
 
@@ -58,17 +57,22 @@ func AIPRCommand() {
 		return
 	}
 
-	prompt := fmt.Sprintf(aiPRPromptTemplate, details.Title, details.Author, details.Body, details.Diff)
-	aiResponse, err := runAITask(prompt, false)
+	buildPrompt := func(diff string) []ai.Message {
+		return ai.UserPrompt(fmt.Sprintf(aiPRPromptTemplate, details.Title, details.Author, details.Body, diff))
+	}
+	title := fmt.Sprintf("🤖 AI Summary of PR #%s", prNumber)
+	ctx, cancel := commandContext("pr")
+	defer cancel()
+	diff, summarized, err := summarizeLargeDiff(ctx, "pr", details.Diff, details.GitArgs(), buildPrompt, false)
 	if err != nil {
-		fmt.Printf("%s %v\n", red("Error:"), err)
+		printAIError(err)
 		return
 	}
-
-	cleanMsg := strings.TrimSpace(aiResponse)
-	viewerModel := NewAITextViewerModel(fmt.Sprintf("🤖 AI Summary of PR #%s", prNumber), cleanMsg)
-	p := tea.NewProgram(viewerModel, tea.WithAltScreen())
-	if _, err := p.Run(); err != nil {
-		fmt.Printf("%s Error displaying AI response: %v\n", red("Error:"), err)
+	var shrink diffShrinker
+	if !summarized {
+		shrink = newDiffShrinker(diff, buildPrompt)
+	}
+	if err := runAITaskInViewer(ctx, "pr", title, buildPrompt(diff), shrink); err != nil {
+		printAIError(err)
 	}
 }
@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"strings"
 	"time"
 
 	"github.com/atotto/clipboard" //
@@ -10,6 +11,8 @@ import (
 	"github.com/charmbracelet/lipgloss"
 )
 
+const viewerRenderInterval = 100 * time.Millisecond
+
 var (
 	titleStyleViewer = lipgloss.NewStyle().
 				Bold(true).
@@ -24,36 +27,99 @@ var (
 				Foreground(lipgloss.Color("46")).
 				Padding(1, 1).
 				Bold(true)
+
+	errorStyleViewer = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("196")).
+				Padding(1, 1).
+				Bold(true)
+
+	reasoningStyleViewer = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("241")).
+				Italic(true).
+				Padding(1, 2)
 )
 
 type copiedMessage struct{}
 
+type aiStreamChunkMsg string
+
+type aiStreamStatusMsg string
+
+type aiStreamReasoningMsg string
+
+type aiStreamProviderMsg string
+
+type aiStreamDoneMsg struct {
+	err  error
+	note string
+}
+
+type viewerRenderTickMsg struct{}
+
 type AITextViewerModel struct {
 	viewport      viewport.Model
 	rawContent    string
+	reasoning     string
 	title         string
 	showingCopied bool
+	streaming     bool
+	dirty         bool
+	status        string
+	provider      string
+	started       time.Time
+	frame         int
+	err           error
 }
 
 func NewAITextViewerModel(title, content string) AITextViewerModel {
 	const width = 100
 	vp := viewport.New(width, 20)
 
-	renderedContent, err := glamour.Render(content, "dark")
-	if err != nil {
-		renderedContent = content
-	}
-
-	vp.SetContent(renderedContent)
-
-	return AITextViewerModel{
+	m := AITextViewerModel{
 		viewport:   vp,
 		rawContent: content,
 		title:      title,
 	}
+	m.render()
+
+	return m
+}
+
+func NewStreamingAITextViewerModel(title string) AITextViewerModel {
+	m := NewAITextViewerModel(title, "")
+	m.streaming = true
+	m.started = time.Now()
+	return m
+}
+
+func (m *AITextViewerModel) render() {
+	renderedContent, err := glamour.Render(m.rawContent, "dark")
+	if err != nil {
+		renderedContent = m.rawContent
+	}
+	if m.reasoning != "" {
+		renderedContent = reasoningStyleViewer.Width(m.viewport.Width-4).Render("Reasoning\n\n"+strings.TrimSpace(m.reasoning)) +
+			"\n" + renderedContent
+	}
+
+	atBottom := m.viewport.AtBottom()
+	m.viewport.SetContent(renderedContent)
+	if m.streaming && atBottom {
+		m.viewport.GotoBottom()
+	}
+	m.dirty = false
+}
+
+func renderTick() tea.Cmd {
+	return tea.Tick(viewerRenderInterval, func(t time.Time) tea.Msg {
+		return viewerRenderTickMsg{}
+	})
 }
 
 func (m AITextViewerModel) Init() tea.Cmd {
+	if m.streaming {
+		return renderTick()
+	}
 	return nil
 }
 
@@ -68,13 +134,50 @@ func (m AITextViewerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.showingCopied = false
 		return m, nil
 
+	case aiStreamChunkMsg:
+		m.rawContent += string(msg)
+		m.dirty = true
+		m.status = ""
+		return m, nil
+
+	case aiStreamReasoningMsg:
+		m.reasoning += string(msg)
+		m.dirty = true
+		m.status = ""
+		return m, nil
+
+	case aiStreamStatusMsg:
+		m.status = string(msg)
+		return m, nil
+
+	case aiStreamProviderMsg:
+		m.provider = string(msg)
+		return m, nil
+
+	case aiStreamDoneMsg:
+		m.err = msg.err
+		m.status = msg.note
+		m.render()
+		m.streaming = false
+		return m, nil
+
+	case viewerRenderTickMsg:
+		if !m.streaming {
+			return m, nil
+		}
+		if m.dirty {
+			m.render()
+		}
+		m.frame++
+		return m, renderTick()
+
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "ctrl+c", "q", "esc":
 			return m, tea.Quit
 
 		case "c":
-			if !m.showingCopied {
+			if !m.showingCopied && !m.streaming {
 				m.showingCopied = true
 				_ = clipboard.WriteAll(m.rawContent)
 				cmds = append(cmds, tea.Tick(time.Second*1, func(t time.Time) tea.Msg {
@@ -116,9 +219,28 @@ func (m AITextViewerModel) headerView() string {
 }
 
 func (m AITextViewerModel) footerView() string {
+	if m.err != nil {
+		return errorStyleViewer.Render("✗ " + m.err.Error() + " • Quit: q")
+	}
+	if m.streaming && m.status != "" {
+		return helpStyleViewer.Render(m.progress() + " " + m.status + " • Quit: q")
+	}
+	if m.streaming && m.rawContent == "" && m.provider != "" {
+		return helpStyleViewer.Render(m.progress() + " Thinking with " + m.provider + " • Quit: q")
+	}
+	if m.streaming {
+		return helpStyleViewer.Render(m.progress() + " Generating... • Scroll: ↑/↓ • Quit: q")
+	}
+	if m.status != "" {
+		return helpStyleViewer.Render(m.status + " • Scroll: ↑/↓ • Copy: c • Quit: q")
+	}
 	return helpStyleViewer.Render("Scroll: ↑/↓ • Copy: c • Quit: q")
 }
 
+func (m AITextViewerModel) progress() string {
+	return spinnerFrame(m.frame) + " " + formatElapsed(time.Since(m.started))
+}
+
 func (m AITextViewerModel) copiedView() string {
 	return copiedStyleViewer.Render("✓ Copied to clipboard!")
 }
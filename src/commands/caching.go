@@ -8,16 +8,14 @@ import (
 	"path/filepath"
 )
 
-func getCacheDir() (string, error) {
+func findGitRoot() (string, error) {
 	dir, err := os.Getwd()
 	if err != nil {
 		return "", err
 	}
-	var gitRoot string
 	for {
 		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
-			gitRoot = dir
-			break
+			return dir, nil
 		}
 		parent := filepath.Dir(dir)
 		if parent == dir {
@@ -25,6 +23,13 @@ func getCacheDir() (string, error) {
 		}
 		dir = parent
 	}
+}
+
+func getCacheDir() (string, error) {
+	gitRoot, err := findGitRoot()
+	if err != nil {
+		return "", err
+	}
 
 	cacheDir := filepath.Join(gitRoot, ".gct", "cache")
 	if err := os.MkdirAll(cacheDir, 0755); err != nil {
@@ -57,6 +62,14 @@ func writeToCache(key string, content string) {
 		return
 	}
 
-	filePath := filepath.Join(cacheDir, key)
-	_ = os.WriteFile(filePath, []byte(content), 0644)
+	tmp, err := os.CreateTemp(cacheDir, key+".*.tmp")
+	if err != nil {
+		return
+	}
+	_, writeErr := tmp.WriteString(content)
+	closeErr := tmp.Close()
+	if writeErr != nil || closeErr != nil || os.Chmod(tmp.Name(), 0644) != nil ||
+		os.Rename(tmp.Name(), filepath.Join(cacheDir, key)) != nil {
+		_ = os.Remove(tmp.Name())
+	}
 }
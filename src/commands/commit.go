@@ -52,8 +52,8 @@ func executeGitCommit(subjectLine, body string, isAmend bool) error {
 	fmt.Printf("\n%s Running git command...\n", cyan("ℹ"))
 	err := executeCommand(cmdToExecute)
 	if err != nil {
-		fmt.Printf("%s Failed to commit: %%v\n", red("✗"), err)
-		fmt.Printf("%s Command attempted: %%s\n", red("↪"), cmdToExecute)
+		fmt.Printf("%s Failed to commit: %v\n", red("✗"), err)
+		fmt.Printf("%s Command attempted: %s\n", red("↪"), cmdToExecute)
 		return err
 	}
 	return nil
@@ -69,7 +69,7 @@ func EditCommitCommand() {
 	cmd := exec.Command("git", "log", "-1", "--pretty=format:%s"+delimiter+"%b")
 	output, err := cmd.Output()
 	if err != nil {
-		fmt.Printf("%s Could not get last commit: %%v\n", color.RedString("✗"), err)
+		fmt.Printf("%s Could not get last commit: %v\n", color.RedString("✗"), err)
 		return
 	}
 
@@ -85,7 +85,7 @@ func runCommitTUI(tuiModel CommitTUIModel, isAmend bool) {
 	p := tea.NewProgram(tuiModel, tea.WithAltScreen())
 	finalModel, err := p.Run()
 	if err != nil {
-		fmt.Printf("Error running TUI: %%v\n", err)
+		fmt.Printf("Error running TUI: %v\n", err)
 		return
 	}
 
@@ -0,0 +1,200 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+)
+
+type partialField struct {
+	value    string
+	complete bool
+}
+
+type commitStreamPrinter struct {
+	reply string
+	shown string
+}
+
+func (p *commitStreamPrinter) write(chunk string) {
+	cyan := color.New(color.FgCyan).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+	green := color.New(color.FgGreen).SprintFunc()
+
+	p.reply += chunk
+	preview := partialCommitPreview(parsePartialJSONStrings(p.reply))
+	if len(preview) <= len(p.shown) || !strings.HasPrefix(preview, p.shown) {
+		return
+	}
+	if p.shown == "" {
+		fmt.Printf("\n%s AI Generated Commit Message:\n%s\n", cyan("🤖"), yellow("--- Start ---"))
+	}
+	fmt.Print(green(preview[len(p.shown):]))
+	p.shown = preview
+}
+
+func (p *commitStreamPrinter) finish() {
+	yellow := color.New(color.FgYellow).SprintFunc()
+	if p.shown != "" {
+		fmt.Printf("\n%s\n", yellow("--- End ---"))
+	}
+}
+
+func (p *commitStreamPrinter) showed(msg commitMessage) bool {
+	shown := strings.TrimSpace(p.shown)
+	if shown == "" {
+		return false
+	}
+	if shown == msg.String() {
+		return true
+	}
+
+	unmarked := msg
+	unmarked.Breaking = false
+	if !msg.Breaking || shown != unmarked.String() {
+		return false
+	}
+	yellow := color.New(color.FgYellow).SprintFunc()
+	green := color.New(color.FgGreen).SprintFunc()
+	fmt.Printf("%s %s\n", yellow("Breaking change:"), green(msg.subjectLine()))
+	return true
+}
+
+func partialCommitPreview(fields map[string]partialField) string {
+	commitType, scope, subject, body := fields["type"], fields["scope"], fields["subject"], fields["body"]
+	if !commitType.complete || !scope.complete || subject.value == "" {
+		return ""
+	}
+
+	preview := strings.TrimSpace(commitType.value)
+	if s := strings.TrimSpace(scope.value); s != "" {
+		preview += "(" + s + ")"
+	}
+	preview += ": " + strings.TrimLeft(subject.value, " ")
+	if !subject.complete {
+		return preview
+	}
+	preview = strings.TrimRight(preview, " ")
+
+	if text := strings.TrimLeft(body.value, " \n"); text != "" {
+		preview += "\n\n" + text
+	}
+	if !body.complete {
+		return preview
+	}
+	preview = strings.TrimRight(preview, " \n")
+
+	var footers []commitFooter
+	if raw := fields["footers"]; raw.complete && json.Unmarshal([]byte(raw.value), &footers) == nil && len(footers) > 0 {
+		preview += "\n\n" + commitMessage{Footers: footers}.fullBody()
+	}
+	return preview
+}
+
+func parsePartialJSONStrings(text string) map[string]partialField {
+	fields := map[string]partialField{}
+	start := strings.Index(text, "{")
+	if start < 0 {
+		return fields
+	}
+
+	s := text[start+1:]
+	for {
+		s = strings.TrimLeft(s, " \t\r\n,")
+		if !strings.HasPrefix(s, "\"") {
+			return fields
+		}
+		key, rest, complete := readJSONString(s)
+		if !complete {
+			return fields
+		}
+		s = strings.TrimLeft(rest, " \t\r\n")
+		if !strings.HasPrefix(s, ":") {
+			return fields
+		}
+		s = strings.TrimLeft(s[1:], " \t\r\n")
+
+		if strings.HasPrefix(s, "\"") {
+			value, rest, complete := readJSONString(s)
+			fields[key] = partialField{value: value, complete: complete}
+			if !complete {
+				return fields
+			}
+			s = rest
+			continue
+		}
+		end, complete := skipJSONValue(s)
+		if !complete {
+			return fields
+		}
+		fields[key] = partialField{value: strings.TrimSpace(s[:end]), complete: true}
+		s = s[end:]
+	}
+}
+
+func readJSONString(s string) (string, string, bool) {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 >= len(s) || (s[i+1] == 'u' && i+6 > len(s)) {
+				return decodeJSONString(trimPartialRune(s[1:i])), "", false
+			}
+			i++
+		case '"':
+			return decodeJSONString(s[1:i]), s[i+1:], true
+		}
+	}
+	return decodeJSONString(trimPartialRune(s[1:])), "", false
+}
+
+func trimPartialRune(raw string) string {
+	for i := 1; i <= utf8.UTFMax && i <= len(raw); i++ {
+		if utf8.RuneStart(raw[len(raw)-i]) {
+			if !utf8.FullRuneInString(raw[len(raw)-i:]) {
+				return raw[:len(raw)-i]
+			}
+			break
+		}
+	}
+	return raw
+}
+
+func decodeJSONString(raw string) string {
+	var value string
+	if err := json.Unmarshal([]byte(`"`+raw+`"`), &value); err != nil {
+		return ""
+	}
+	return value
+}
+
+func skipJSONValue(s string) (int, bool) {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			_, rest, complete := readJSONString(s[i:])
+			if !complete {
+				return 0, false
+			}
+			i = len(s) - len(rest) - 1
+		case '{', '[':
+			depth++
+		case '}', ']':
+			if depth == 0 {
+				return i, true
+			}
+			depth--
+			if depth == 0 {
+				return i + 1, true
+			}
+		case ',':
+			if depth == 0 {
+				return i, true
+			}
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,114 @@
+package commands
+
+import (
+	"testing"
+)
+
+func TestParsePartialJSONStrings(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want map[string]partialField
+	}{
+		{
+			name: "complete object with escapes",
+			text: `{"type": "fix", "subject": "quote \"x\" and tab\t", "body": "line\nnext é"}`,
+			want: map[string]partialField{
+				"type":    {value: "fix", complete: true},
+				"subject": {value: "quote \"x\" and tab\t", complete: true},
+				"body":    {value: "line\nnext é", complete: true},
+			},
+		},
+		{
+			name: "truncated inside a string",
+			text: `{"type":"feat","scope":"","subject":"add sup`,
+			want: map[string]partialField{
+				"type":    {value: "feat", complete: true},
+				"scope":   {value: "", complete: true},
+				"subject": {value: "add sup", complete: false},
+			},
+		},
+		{
+			name: "truncated inside an escape",
+			text: `{"subject":"a \u00`,
+			want: map[string]partialField{"subject": {value: "a ", complete: false}},
+		},
+		{
+			name: "truncated inside a UTF-8 rune",
+			text: "{\"subject\":\"caf\xc3",
+			want: map[string]partialField{"subject": {value: "caf", complete: false}},
+		},
+		{
+			name: "nested values",
+			text: `{"breaking": true, "footers": [{"token": "Refs", "value": "#1, #2"}], "type": "fix"}`,
+			want: map[string]partialField{
+				"breaking": {value: "true", complete: true},
+				"footers":  {value: `[{"token": "Refs", "value": "#1, #2"}]`, complete: true},
+				"type":     {value: "fix", complete: true},
+			},
+		},
+		{
+			name: "truncated nested value",
+			text: `{"type": "fix", "footers": [{"token": "Refs", "val`,
+			want: map[string]partialField{"type": {value: "fix", complete: true}},
+		},
+		{
+			name: "text before the object",
+			text: "```json\n{\"type\": \"docs\"",
+			want: map[string]partialField{"type": {value: "docs", complete: true}},
+		},
+		{
+			name: "no object yet",
+			text: "```js",
+			want: map[string]partialField{},
+		},
+	}
+	for _, tt := range tests {
+		got := parsePartialJSONStrings(tt.text)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for key, want := range tt.want {
+			if got[key] != want {
+				t.Errorf("%s: %s = %+v, want %+v", tt.name, key, got[key], want)
+			}
+		}
+	}
+}
+
+func TestPartialCommitPreview(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{`{"type": "feat", "scope": "ai`, ""},
+		{`{"type": "feat", "scope": "ai", "subject": "stream the`, "feat(ai): stream the"},
+		{`{"type": "feat", "scope": "", "subject": "stream ", "body": "Show it`, "feat: stream\n\nShow it"},
+		{`{"type": "fix", "scope": "", "subject": "a", "body": "b", "breaking": false, "footers": [{"token": "Refs", "value": "#3"}]}`,
+			"fix: a\n\nb\n\nRefs: #3"},
+	}
+	for _, tt := range tests {
+		if got := partialCommitPreview(parsePartialJSONStrings(tt.text)); got != tt.want {
+			t.Errorf("partialCommitPreview(%s) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestCommitStreamPrinterShowed(t *testing.T) {
+	msg := commitMessage{Type: "feat", Scope: "api", Subject: "drop v1", Body: "Remove the old routes."}
+	printer := &commitStreamPrinter{shown: "feat(api): drop v1\n\nRemove the old routes."}
+	if !printer.showed(msg) {
+		t.Fatal("the streamed message should not be printed again")
+	}
+
+	msg.Breaking = true
+	if !printer.showed(msg) {
+		t.Fatal("a breaking message should only add the breaking marker")
+	}
+
+	msg.Subject = "remove v1"
+	if printer.showed(msg) {
+		t.Fatal("a different message should be printed")
+	}
+}
@@ -0,0 +1,424 @@
+package commands
+
+import (
+	"fmt"
+	"gct/src/ai"
+	"gct/src/config"
+	"os"
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/fatih/color"
+)
+
+const defaultOutputReserve = 4096
+
+const maxListedFiles = 5
+
+var lowValueFileNames = []string{
+	"package-lock.json", "npm-shrinkwrap.json", "yarn.lock", "pnpm-lock.yaml", "bun.lockb",
+	"go.sum", "Cargo.lock", "poetry.lock", "Pipfile.lock", "uv.lock", "composer.lock",
+	"Gemfile.lock", "mix.lock", "pubspec.lock", "Podfile.lock", "flake.lock",
+}
+
+var lowValueFileSuffixes = []string{".min.js", ".min.css", ".map", ".snap", ".pb.go", "_generated.go", ".gen.go"}
+
+var lowValueDirs = []string{"vendor/", "node_modules/", "dist/"}
+
+var lowPriorityDirs = []string{"docs/", "doc/", "test/", "tests/", "testdata/", "__tests__/"}
+
+var whitespaceSensitiveSuffixes = []string{".py", ".pyi", ".pyx", ".yaml", ".yml", ".mk", ".md", ".rst", ".haml", ".slim",
+	".pug", ".jade", ".sass", ".styl", ".coffee", ".nim", ".fs", ".fsx", ".elm", ".hs", ".tsv"}
+
+var whitespaceSensitiveNames = []string{"Makefile", "GNUmakefile", "makefile", "Snakefile", "Tiltfile", "BUILD", "WORKSPACE"}
+
+var reducedContextLines = []int{1, 0}
+
+type diffFitter struct {
+	cfg     *config.Config
+	gitArgs []string
+	build   func(diff string) []ai.Message
+	budget  int
+	notes   []string
+}
+
+func fitDiffToModel(command, diff string, gitArgs []string, build func(diff string) []ai.Message, isSilent bool) string {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return diff
+	}
+	fitted, notes := fitDiff(cfg, command, diff, gitArgs, build)
+	printDiffFitNotes(notes, isSilent)
+	return fitted
+}
+
+func newDiffFitter(cfg *config.Config, command string, gitArgs []string, build func(diff string) []ai.Message) *diffFitter {
+	return &diffFitter{
+		cfg:     cfg,
+		gitArgs: gitArgs,
+		build:   build,
+		budget:  promptBudget(cfg, command),
+	}
+}
+
+func fitDiff(cfg *config.Config, command, diff string, gitArgs []string, build func(diff string) []ai.Message) (string, []string) {
+	f := newDiffFitter(cfg, command, gitArgs, build)
+	if f.budget <= 0 || f.fits(diff) {
+		return diff, nil
+	}
+	return f.fit(diff), f.notes
+}
+
+func promptBudget(cfg *config.Config, command string) int {
+	spec := ai.LookupModel(cfg, cfg.ProviderConfig)
+	if spec.ContextWindow <= 0 {
+		return 0
+	}
+
+	params := cfg.Generation.ForCommand(command)
+	reserve := params.MaxTokens
+	if reserve <= 0 {
+		reserve = defaultOutputReserve
+		if spec.MaxOutputTokens > 0 {
+			reserve = min(reserve, spec.MaxOutputTokens)
+		}
+	}
+	reserve += params.ThinkingBudget
+
+	return min(spec.ContextWindow-reserve, int(float64(spec.ContextWindow)*contextWarningRatio))
+}
+
+func (f *diffFitter) fits(diff string) bool {
+	return ai.CountTokens(f.cfg.ProviderConfig, f.build(diff)).Tokens <= f.budget
+}
+
+func (f *diffFitter) trimLowValue(diff string) ([]diffFile, bool) {
+	all := splitDiffFiles(diff)
+	files, dropped := dropLowValueFiles(all)
+	if len(files) == 0 {
+		return all, false
+	}
+	if len(dropped) > 0 {
+		f.notes = append(f.notes, fmt.Sprintf("Left out %d lock, generated or binary %s: %s",
+			len(dropped), pluralize(len(dropped), "file", "files"), listFiles(dropped)))
+	}
+	files, hunks := dropWhitespaceHunks(files)
+	if hunks > 0 {
+		f.notes = append(f.notes, fmt.Sprintf("Left out %d whitespace-only %s", hunks, pluralize(hunks, "hunk", "hunks")))
+	}
+	return files, true
+}
+
+func (f *diffFitter) fit(diff string) string {
+	reduced, files, ok := f.reduce(diff)
+	if ok {
+		return reduced
+	}
+	return f.summarize(files)
+}
+
+func (f *diffFitter) reduce(diff string) (string, []diffFile, bool) {
+	files, ok := f.trimLowValue(diff)
+	if !ok {
+		f.notes = append(f.notes, fmt.Sprintf("Sent only a summary of %d lock, generated or binary %s",
+			len(files), pluralize(len(files), "file", "files")))
+		return diffStat(files), files, true
+	}
+	diff = joinDiffFiles(files)
+	if f.fits(diff) {
+		return diff, files, true
+	}
+
+	for _, lines := range reducedContextLines {
+		reduced, ok := f.reduceContext(lines)
+		if !ok {
+			break
+		}
+		files = reduced
+		diff = joinDiffFiles(files)
+		if f.fits(diff) || lines == 0 {
+			f.notes = append(f.notes, fmt.Sprintf("Reduced the diff context to %d %s around each change",
+				lines, pluralize(lines, "line", "lines")))
+		}
+		if f.fits(diff) {
+			return diff, files, true
+		}
+	}
+	return diff, files, false
+}
+
+func (f *diffFitter) reduceContext(lines int) ([]diffFile, bool) {
+	if f.gitArgs == nil {
+		return nil, false
+	}
+	args := append([]string{"diff", fmt.Sprintf("--unified=%d", lines)}, f.gitArgs...)
+	output, err := exec.Command("git", args...).Output()
+	if err != nil || len(output) == 0 {
+		return nil, false
+	}
+	files, _ := dropLowValueFiles(splitDiffFiles(string(output)))
+	files, _ = dropWhitespaceHunks(files)
+	return files, true
+}
+
+func (f *diffFitter) summarize(files []diffFile) string {
+	stat := diffStat(files)
+
+	order := make([]int, len(files))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		pa, pb := isLowPriorityFile(files[order[a]].path()), isLowPriorityFile(files[order[b]].path())
+		if pa != pb {
+			return !pa
+		}
+		return len(files[order[a]].body) < len(files[order[b]].body)
+	})
+
+	used := ai.CountTokens(f.cfg.ProviderConfig, f.build(stat)).Tokens
+	selected := make([]bool, len(files))
+	for _, i := range order {
+		tokens := ai.CountTextTokens(f.cfg.ProviderConfig, files[i].header+files[i].body)
+		if used+tokens <= f.budget {
+			selected[i] = true
+			used += tokens
+		}
+	}
+
+	var omitted []string
+	for i, file := range files {
+		if !selected[i] {
+			omitted = append(omitted, file.path())
+		}
+	}
+	if len(omitted) > 0 {
+		f.notes = append(f.notes, fmt.Sprintf("Sent a summary of all %d files, with the changes of %d; left out the changes of %s",
+			len(files), len(files)-len(omitted), listFiles(omitted)))
+	}
+	return stat + joinSelectedFiles(files, selected)
+}
+
+func (d diffFile) path() string {
+	line, _, _ := strings.Cut(d.header, "\n")
+	if idx := strings.LastIndex(line, " b/"); idx >= 0 && strings.HasPrefix(line, "diff --git ") {
+		return line[idx+len(" b/"):]
+	}
+	return ""
+}
+
+func isLowValueFile(file diffFile) bool {
+	if strings.Contains(file.header, "\nBinary files ") || strings.HasPrefix(file.header, "Binary files ") {
+		return true
+	}
+	name := file.path()
+	if name == "" {
+		return false
+	}
+	base := path.Base(name)
+	for _, lockfile := range lowValueFileNames {
+		if base == lockfile {
+			return true
+		}
+	}
+	for _, suffix := range lowValueFileSuffixes {
+		if strings.HasSuffix(base, suffix) {
+			return true
+		}
+	}
+	for _, dir := range lowValueDirs {
+		if strings.HasPrefix(name, dir) || strings.Contains(name, "/"+dir) {
+			return true
+		}
+	}
+	return false
+}
+
+func isLowPriorityFile(name string) bool {
+	for _, dir := range lowPriorityDirs {
+		if strings.HasPrefix(name, dir) || strings.Contains(name, "/"+dir) {
+			return true
+		}
+	}
+	return strings.HasSuffix(name, ".md") || strings.Contains(path.Base(name), "_test.")
+}
+
+func dropLowValueFiles(files []diffFile) ([]diffFile, []string) {
+	var kept []diffFile
+	var dropped []string
+	for _, file := range files {
+		if isLowValueFile(file) {
+			dropped = append(dropped, file.path())
+			continue
+		}
+		kept = append(kept, file)
+	}
+	return kept, dropped
+}
+
+func splitHunks(body string) []string {
+	var hunks []string
+	for _, line := range strings.SplitAfter(body, "\n") {
+		if strings.HasPrefix(line, "@@") || len(hunks) == 0 {
+			hunks = append(hunks, "")
+		}
+		hunks[len(hunks)-1] += line
+	}
+	return hunks
+}
+
+func isWhitespaceHunk(hunk string) bool {
+	var before, after strings.Builder
+	for _, line := range strings.SplitAfter(hunk, "\n") {
+		switch {
+		case strings.HasPrefix(line, "@@"), strings.HasPrefix(line, "\\"):
+		case strings.HasPrefix(line, "-"):
+			before.WriteString(line[1:])
+		case strings.HasPrefix(line, "+"):
+			after.WriteString(line[1:])
+		default:
+			before.WriteString(strings.TrimPrefix(line, " "))
+			after.WriteString(strings.TrimPrefix(line, " "))
+		}
+	}
+	removed, closed := stripCodeWhitespace(before.String())
+	if !closed {
+		return false
+	}
+	added, closed := stripCodeWhitespace(after.String())
+	return closed && removed == added
+}
+
+func stripCodeWhitespace(code string) (string, bool) {
+	var sb strings.Builder
+	var quote rune
+	escaped := false
+	for _, r := range code {
+		switch {
+		case quote == 0 && unicode.IsSpace(r):
+			continue
+		case quote == 0 && (r == '"' || r == '\'' || r == '`'):
+			quote = r
+		case escaped:
+			escaped = false
+		case quote != 0 && quote != '`' && r == '\\':
+			escaped = true
+		case r == quote:
+			quote = 0
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String(), quote == 0
+}
+
+func isWhitespaceSensitive(name string) bool {
+	base := path.Base(name)
+	for _, sensitive := range whitespaceSensitiveNames {
+		if base == sensitive {
+			return true
+		}
+	}
+	for _, suffix := range whitespaceSensitiveSuffixes {
+		if strings.HasSuffix(base, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func dropWhitespaceHunks(files []diffFile) ([]diffFile, int) {
+	dropped := 0
+	kept := make([]diffFile, 0, len(files))
+	for _, file := range files {
+		if isWhitespaceSensitive(file.path()) {
+			kept = append(kept, file)
+			continue
+		}
+		var body strings.Builder
+		for _, hunk := range splitHunks(file.body) {
+			if strings.HasPrefix(hunk, "@@") && isWhitespaceHunk(hunk) {
+				dropped++
+				continue
+			}
+			body.WriteString(hunk)
+		}
+		if file.body != "" && body.Len() == 0 {
+			continue
+		}
+		kept = append(kept, diffFile{header: file.header, body: body.String()})
+	}
+	return kept, dropped
+}
+
+func joinDiffFiles(files []diffFile) string {
+	var sb strings.Builder
+	for _, file := range files {
+		sb.WriteString(file.header)
+		sb.WriteString(file.body)
+	}
+	return sb.String()
+}
+
+func joinSelectedFiles(files []diffFile, selected []bool) string {
+	var sb strings.Builder
+	for i, file := range files {
+		if selected[i] {
+			sb.WriteString(file.header)
+			sb.WriteString(file.body)
+		}
+	}
+	return sb.String()
+}
+
+func diffStat(files []diffFile) string {
+	width := 0
+	for _, file := range files {
+		width = max(width, len(file.path()))
+	}
+
+	var sb strings.Builder
+	insertions, deletions := 0, 0
+	for _, file := range files {
+		added, removed := 0, 0
+		for _, line := range strings.Split(file.body, "\n") {
+			switch {
+			case strings.HasPrefix(line, "+"):
+				added++
+			case strings.HasPrefix(line, "-"):
+				removed++
+			}
+		}
+		insertions += added
+		deletions += removed
+		sb.WriteString(fmt.Sprintf(" %-*s | %d +%d -%d\n", width, file.path(), added+removed, added, removed))
+	}
+	sb.WriteString(fmt.Sprintf(" %d files changed, %d insertions(+), %d deletions(-)\n\n", len(files), insertions, deletions))
+	return sb.String()
+}
+
+func listFiles(names []string) string {
+	if len(names) <= maxListedFiles {
+		return strings.Join(names, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(names[:maxListedFiles], ", "), len(names)-maxListedFiles)
+}
+
+func pluralize(n int, singular, plural string) string {
+	if n == 1 {
+		return singular
+	}
+	return plural
+}
+
+func printDiffFitNotes(notes []string, isSilent bool) {
+	for _, note := range notes {
+		if isSilent {
+			fmt.Fprintf(os.Stderr, "gct: %s\n", note)
+			continue
+		}
+		fmt.Printf("%s %s\n", color.YellowString("✂"), note)
+	}
+}
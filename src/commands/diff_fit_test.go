@@ -0,0 +1,157 @@
+package commands
+
+import (
+	"fmt"
+	"gct/src/ai"
+	"gct/src/config"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func promptOf(diff string) []ai.Message {
+	return ai.UserPrompt(diff)
+}
+
+func fitTestConfig(fits string) *config.Config {
+	cfg := &config.Config{
+		ProviderConfig: config.ProviderConfig{Provider: "Fake", Model: "test-model"},
+		Generation:     config.GenerationConfig{GenerationParams: config.GenerationParams{MaxTokens: 1}},
+	}
+	cfg.ContextWindow = (ai.CountTokens(cfg.ProviderConfig, promptOf(fits)).Tokens + 1) * 5 / 4
+	return cfg
+}
+
+func fileDiff(name, body string) string {
+	return fmt.Sprintf("diff --git a/%s b/%s\n--- a/%s\n+++ b/%s\n%s", name, name, name, name, body)
+}
+
+func TestIsWhitespaceHunk(t *testing.T) {
+	tests := []struct {
+		name string
+		hunk string
+		want bool
+	}{
+		{"reindented code", "@@ -1,3 +1,3 @@\n func f() {\n-return x\n+\treturn x\n }\n", true},
+		{"trailing whitespace", "@@ -1 +1 @@\n-x := 1 \n+x := 1\n\\ No newline at end of file\n", true},
+		{"changed code", "@@ -1 +1 @@\n-return x\n+return y\n", false},
+		{"spaces in a string", "@@ -1 +1 @@\n-msg := \"a  b\"\n+msg := \"a b\"\n", false},
+		{"spaces around a string", "@@ -1 +1 @@\n-msg :=\"a b\"\n+msg := \"a b\"\n", true},
+		{"escaped quote", "@@ -1 +1 @@\n-s := \"\\\" a\"\n+s := \"\\\"  a\"\n", false},
+		{"reindented SQL in a raw string", "@@ -1,4 +1,4 @@\n query := `\n-SELECT id\n-FROM users\n+  SELECT id\n+  FROM users\n `\n", false},
+		{"hunk starting inside a string", "@@ -5,2 +5,2 @@\n-  FROM users\n+FROM users\n `\n", false},
+	}
+	for _, tt := range tests {
+		if got := isWhitespaceHunk(tt.hunk); got != tt.want {
+			t.Errorf("%s: isWhitespaceHunk = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDropWhitespaceHunks(t *testing.T) {
+	reindent := "@@ -1,2 +1,2 @@\n-if x:\n-  y()\n+if x:\n+    y()\n"
+	change := "@@ -10 +10 @@\n-a = 1\n+a = 2\n"
+	files := splitDiffFiles(fileDiff("main.go", reindent+change) + fileDiff("tool.py", reindent) + fileDiff("util.go", reindent))
+
+	kept, dropped := dropWhitespaceHunks(files)
+	if dropped != 2 {
+		t.Errorf("dropped %d hunks, want 2", dropped)
+	}
+	if len(kept) != 2 || kept[0].path() != "main.go" || kept[1].path() != "tool.py" {
+		t.Fatalf("kept %v, want main.go and tool.py", kept)
+	}
+	if kept[0].body != change {
+		t.Errorf("main.go body = %q, want only the real change", kept[0].body)
+	}
+	if kept[1].body != reindent {
+		t.Errorf("an indentation change in a Python file should be kept, got %q", kept[1].body)
+	}
+}
+
+func TestFitDiffDropsLowValueFiles(t *testing.T) {
+	code := fileDiff("main.go", "@@ -1 +1 @@\n-return x\n+return y\n")
+	lock := fileDiff("go.sum", "@@ -1 +1,200 @@\n"+strings.Repeat("+example.com/mod v1.0.0 h1:abcdefghijklmnopqrstuvwxyz0123456789=\n", 200))
+
+	fitted, notes := fitDiff(fitTestConfig(code), "diff", code+lock, nil, promptOf)
+	if fitted != code {
+		t.Fatalf("fitted diff = %q, want only main.go", fitted)
+	}
+	if len(notes) != 1 || !strings.Contains(notes[0], "Left out 1 lock, generated or binary file: go.sum") {
+		t.Errorf("notes = %q", notes)
+	}
+}
+
+func TestFitDiffSendsStatForLowValueFiles(t *testing.T) {
+	lock := fileDiff("go.sum", "@@ -1 +1,200 @@\n"+strings.Repeat("+example.com/mod v1.0.0 h1:abcdefghijklmnopqrstuvwxyz0123456789=\n", 200))
+
+	fitted, notes := fitDiff(fitTestConfig(strings.Repeat("x", 200)), "diff", lock, nil, promptOf)
+	want := " go.sum | 200 +200 -0\n 1 files changed, 200 insertions(+), 0 deletions(-)\n\n"
+	if fitted != want {
+		t.Fatalf("fitted diff = %q, want %q", fitted, want)
+	}
+	if len(notes) != 1 || !strings.HasPrefix(notes[0], "Sent only a summary of 1 lock") {
+		t.Errorf("notes = %q", notes)
+	}
+}
+
+func TestFitDiffSummarizesWhatDoesNotFit(t *testing.T) {
+	small := fileDiff("small.go", "@@ -1 +1 @@\n-a\n+b\n")
+	large := fileDiff("large.go", "@@ -1 +1,100 @@\n"+strings.Repeat("+line of new code that makes this file large\n", 100))
+	stat := diffStat(splitDiffFiles(small + large))
+
+	fitted, notes := fitDiff(fitTestConfig(stat+small), "diff", small+large, nil, promptOf)
+	if fitted != stat+small {
+		t.Fatalf("fitted diff = %q, want the stat and small.go", fitted)
+	}
+	if len(notes) != 1 || !strings.Contains(notes[0], "left out the changes of large.go") {
+		t.Errorf("notes = %q", notes)
+	}
+}
+
+func TestFitDiffReducesContext(t *testing.T) {
+	t.Chdir(t.TempDir())
+	git := func(args ...string) string {
+		out, err := exec.Command("git", append([]string{"-c", "user.name=t", "-c", "user.email=t@example.com", "-c", "commit.gpgsign=false"}, args...)...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+		return string(out)
+	}
+
+	var lines []string
+	for i := 0; i < 60; i++ {
+		lines = append(lines, fmt.Sprintf("context line number %d with some text", i))
+	}
+	git("init", "-q")
+	if err := os.WriteFile("file.txt", []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git("add", ".")
+	git("commit", "-qm", "init")
+	for _, i := range []int{5, 20, 35, 50} {
+		lines[i] = "changed"
+	}
+	if err := os.WriteFile("file.txt", []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	full := git("diff")
+	reduced := git("diff", "--unified=1")
+	cfg := fitTestConfig(reduced)
+	if len(full) <= len(reduced) {
+		t.Fatal("the full diff should be larger than the reduced one")
+	}
+
+	fitted, notes := fitDiff(cfg, "diff", full, []string{}, promptOf)
+	if fitted != reduced {
+		t.Fatalf("fitted diff = %q, want %q", fitted, reduced)
+	}
+	if len(notes) != 1 || notes[0] != "Reduced the diff context to 1 line around each change" {
+		t.Errorf("notes = %q", notes)
+	}
+
+	if _, notes := fitDiff(cfg, "diff", full, nil, promptOf); len(notes) != 1 || !strings.HasPrefix(notes[0], "Sent a summary") {
+		t.Errorf("without git arguments the context cannot be reduced, notes = %q", notes)
+	}
+}
@@ -0,0 +1,98 @@
+package commands
+
+import (
+	"fmt"
+	"gct/src/ai"
+	"sort"
+	"strings"
+)
+
+const maxDiffReductions = 3
+
+const diffOmittedNote = "... [%d lines omitted to fit the model's context window]\n"
+
+type diffShrinker func() ([]ai.Message, bool)
+
+func newDiffShrinker(diff string, build func(diff string) []ai.Message) diffShrinker {
+	limit := len(diff)
+	rounds := 0
+	return func() ([]ai.Message, bool) {
+		if rounds >= maxDiffReductions {
+			return nil, false
+		}
+		rounds++
+		limit /= 2
+		return build(reduceDiff(diff, limit)), true
+	}
+}
+
+type diffFile struct {
+	header string
+	body   string
+}
+
+func splitDiffFiles(diff string) []diffFile {
+	var files []diffFile
+	for _, chunk := range strings.SplitAfter(diff, "\n") {
+		if strings.HasPrefix(chunk, "diff --git ") || len(files) == 0 {
+			files = append(files, diffFile{})
+		}
+		file := &files[len(files)-1]
+		if file.body == "" && !strings.HasPrefix(chunk, "@@") {
+			file.header += chunk
+		} else {
+			file.body += chunk
+		}
+	}
+	return files
+}
+
+func reduceDiff(diff string, limit int) string {
+	files := splitDiffFiles(diff)
+
+	order := make([]int, len(files))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool {
+		return len(files[order[a]].body) < len(files[order[b]].body)
+	})
+
+	for _, file := range files {
+		limit -= len(file.header)
+	}
+
+	allowance := make([]int, len(files))
+	for n, i := range order {
+		share := 0
+		if limit > 0 {
+			share = limit / (len(order) - n)
+		}
+		allowance[i] = min(len(files[i].body), share)
+		limit -= allowance[i]
+	}
+
+	var sb strings.Builder
+	for i, file := range files {
+		sb.WriteString(file.header)
+		if allowance[i] >= len(file.body) {
+			sb.WriteString(file.body)
+			continue
+		}
+		sb.WriteString(truncateLines(file.body, allowance[i]))
+	}
+	return sb.String()
+}
+
+func truncateLines(text string, limit int) string {
+	if limit >= len(text) {
+		return text
+	}
+	kept := text[:max(limit, 0)]
+	if cut := strings.LastIndex(kept, "\n"); cut >= 0 {
+		kept = kept[:cut+1]
+	} else {
+		kept = ""
+	}
+	return kept + fmt.Sprintf(diffOmittedNote, strings.Count(text[len(kept):], "\n"))
+}
@@ -0,0 +1,58 @@
+package commands
+
+import (
+	"gct/src/ai"
+	"strings"
+	"testing"
+)
+
+func TestTruncateLines(t *testing.T) {
+	text := "one\ntwo\nthree\nfour\n"
+	if got := truncateLines(text, len(text)); got != text {
+		t.Errorf("text within the limit should be kept, got %q", got)
+	}
+	if got, want := truncateLines(text, 9), "one\ntwo\n... [2 lines omitted to fit the model's context window]\n"; got != want {
+		t.Errorf("truncateLines = %q, want %q", got, want)
+	}
+	if got, want := truncateLines(text, 2), "... [4 lines omitted to fit the model's context window]\n"; got != want {
+		t.Errorf("truncateLines = %q, want %q", got, want)
+	}
+}
+
+func TestReduceDiffKeepsHeadersAndSmallFiles(t *testing.T) {
+	small := fileDiff("small.go", "@@ -1 +1 @@\n-a\n+b\n")
+	large := fileDiff("large.go", "@@ -1 +1,100 @@\n"+strings.Repeat("+line of new code\n", 100))
+	limit := len(small) + len(large)/2
+
+	reduced := reduceDiff(small+large, limit)
+	if !strings.HasPrefix(reduced, small+"diff --git a/large.go b/large.go\n") {
+		t.Fatalf("the small file and every header should be kept, got %q", reduced)
+	}
+	if !strings.HasSuffix(reduced, "lines omitted to fit the model's context window]\n") {
+		t.Errorf("the large file should end with an omission note, got %q", reduced)
+	}
+	if len(reduced) > limit+len(diffOmittedNote) {
+		t.Errorf("reduced diff is %d bytes, want at most about %d", len(reduced), limit)
+	}
+}
+
+func TestDiffShrinkerHalvesTheDiff(t *testing.T) {
+	diff := fileDiff("large.go", "@@ -1 +1,100 @@\n"+strings.Repeat("+line of new code\n", 100))
+	shrink := newDiffShrinker(diff, func(diff string) []ai.Message { return ai.UserPrompt(diff) })
+
+	previous := len(diff)
+	for round := 1; round <= maxDiffReductions; round++ {
+		messages, ok := shrink()
+		if !ok {
+			t.Fatalf("round %d: the shrinker gave up early", round)
+		}
+		if size := len(messages[0].Content); size >= previous {
+			t.Errorf("round %d: %d bytes, want less than %d", round, size, previous)
+		} else {
+			previous = size
+		}
+	}
+	if _, ok := shrink(); ok {
+		t.Errorf("the shrinker should stop after %d rounds", maxDiffReductions)
+	}
+}
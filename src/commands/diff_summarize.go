@@ -0,0 +1,344 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gct/src/ai"
+	"gct/src/config"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/fatih/color"
+)
+
+const summaryWorkers = 4
+
+const maxSummaryRounds = 3
+
+const aiChunkSummaryPromptTemplate = `
+You are an expert software engineer. A git diff is too large to read at once, so it has been split into parts.
+Summarize part %d of %d below as a concise bullet list. For each file, say what changed and why it matters.
+Call out new features, bug fixes, breaking changes, and removed behavior. Do not add an introduction or a conclusion.
+
+--- DIFF PART START ---
+%s
+--- DIFF PART END ---
+`
+
+const aiSummaryMergePromptTemplate = `
+You are an expert software engineer. The summaries below describe parts of a git diff that is too large to read at once.
+Merge summaries %d of %d below into one concise bullet list. Keep every new feature, bug fix, breaking change, and removed behavior, and drop repetition.
+Do not add an introduction or a conclusion.
+
+--- SUMMARIES START ---
+%s
+--- SUMMARIES END ---
+`
+
+const summarizedDiffTemplate = `The full diff was too large to include, so it was summarized in %d parts. Together the summaries below cover every changed file.
+
+%s`
+
+type diffChunk struct {
+	label  string
+	text   string
+	tokens int
+}
+
+type diffSummarizer struct {
+	cfg      *config.Config
+	task     *aiTask
+	fitter   *diffFitter
+	isSilent bool
+	spinner  *progressSpinner
+}
+
+func summarizeLargeDiff(ctx context.Context, command, diff string, gitArgs []string, build func(diff string) []ai.Message, isSilent bool) (string, bool, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return diff, false, nil
+	}
+
+	f := newDiffFitter(cfg, command, gitArgs, build)
+	if f.budget <= 0 || f.fits(diff) {
+		return diff, false, nil
+	}
+	reduced, files, ok := f.reduce(diff)
+	printDiffFitNotes(f.notes, isSilent)
+	if ok {
+		return reduced, false, nil
+	}
+
+	s := &diffSummarizer{cfg: cfg, task: newAITask(command, cfg, nil), fitter: f, isSilent: isSilent}
+	summary, err := s.run(ctx, files)
+	if err != nil {
+		return "", false, err
+	}
+	return summary, true, nil
+}
+
+func (s *diffSummarizer) run(ctx context.Context, files []diffFile) (string, error) {
+	chunks := s.chunkFiles(files)
+	s.progress(fmt.Sprintf("The diff is too large for %s, summarizing it in %d %s",
+		providerLabel(s.cfg.ProviderConfig), len(chunks), pluralize(len(chunks), "part", "parts")))
+
+	summaries, err := s.summarize(ctx, chunks, aiChunkSummaryPromptTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	for round := 1; ; round++ {
+		document := fmt.Sprintf(summarizedDiffTemplate, len(chunks), joinSummaries(chunks, summaries))
+		if s.fitter.fits(document) {
+			return document, nil
+		}
+		if len(summaries) == 1 || round >= maxSummaryRounds {
+			return s.truncate(document), nil
+		}
+
+		chunks = s.chunkSummaries(chunks, summaries)
+		s.progress(fmt.Sprintf("The summaries are still too large, merging them into %d %s",
+			len(chunks), pluralize(len(chunks), "part", "parts")))
+		if summaries, err = s.summarize(ctx, chunks, aiSummaryMergePromptTemplate); err != nil {
+			return "", err
+		}
+	}
+}
+
+func (s *diffSummarizer) truncate(document string) string {
+	s.progress(fmt.Sprintf("The summaries are still too large for %s, cutting them short to fit",
+		providerLabel(s.cfg.ProviderConfig)))
+
+	truncated := document
+	for limit := len(document); limit > 0 && !s.fitter.fits(truncated); {
+		limit = limit * 9 / 10
+		truncated = truncateLines(document, limit)
+	}
+	return truncated
+}
+
+func (s *diffSummarizer) chunkLimit(template string) int {
+	overhead := ai.CountTokens(s.cfg.ProviderConfig, ai.UserPrompt(fmt.Sprintf(template, 1, 1, ""))).Tokens
+	return max(s.fitter.budget-overhead, 1)
+}
+
+func (s *diffSummarizer) countText(text string) int {
+	return ai.CountTextTokens(s.cfg.ProviderConfig, text)
+}
+
+func (s *diffSummarizer) chunkFiles(files []diffFile) []diffChunk {
+	limit := s.chunkLimit(aiChunkSummaryPromptTemplate)
+
+	var items []diffChunk
+	for _, file := range files {
+		text := file.header + file.body
+		tokens := s.countText(text)
+		if tokens <= limit {
+			items = append(items, diffChunk{label: file.path(), text: text, tokens: tokens})
+			continue
+		}
+		parts := s.splitFile(file, limit)
+		for i, part := range parts {
+			label := file.path()
+			if len(parts) > 1 {
+				label = fmt.Sprintf("%s (part %d of %d)", file.path(), i+1, len(parts))
+			}
+			items = append(items, diffChunk{label: label, text: part, tokens: s.countText(part)})
+		}
+	}
+	return packChunks(items, limit)
+}
+
+func (s *diffSummarizer) splitFile(file diffFile, limit int) []string {
+	room := max(limit-s.countText(file.header), 1)
+
+	var pieces []string
+	for _, hunk := range splitHunks(file.body) {
+		if s.countText(hunk) <= room {
+			pieces = append(pieces, hunk)
+			continue
+		}
+		pieces = append(pieces, s.splitHunk(hunk, room)...)
+	}
+
+	var parts []string
+	current, currentTokens := "", 0
+	for _, piece := range pieces {
+		tokens := s.countText(piece)
+		if current != "" && currentTokens+tokens > room {
+			parts = append(parts, file.header+current)
+			current, currentTokens = "", 0
+		}
+		current += piece
+		currentTokens += tokens
+	}
+	if current != "" {
+		parts = append(parts, file.header+current)
+	}
+	return parts
+}
+
+func (s *diffSummarizer) splitHunk(hunk string, room int) []string {
+	header, _, _ := strings.Cut(hunk, "\n")
+	header += "\n"
+	size := max(len(hunk)*room/s.countText(hunk), 1)
+
+	var pieces []string
+	current := ""
+	for _, line := range strings.SplitAfter(hunk, "\n") {
+		if current != "" && current != header && len(current)+len(line) > size {
+			pieces = append(pieces, current)
+			current = header
+		}
+		current += line
+	}
+	if current != "" && current != header {
+		pieces = append(pieces, current)
+	}
+
+	for i, piece := range pieces {
+		if tokens := s.countText(piece); tokens > room {
+			pieces[i] = truncateLines(piece, len(piece)*room/tokens)
+		}
+	}
+	return pieces
+}
+
+func (s *diffSummarizer) chunkSummaries(chunks []diffChunk, summaries []string) []diffChunk {
+	limit := s.chunkLimit(aiSummaryMergePromptTemplate)
+
+	items := make([]diffChunk, len(chunks))
+	for i, chunk := range chunks {
+		text := fmt.Sprintf("### %s\n%s\n\n", chunk.label, strings.TrimSpace(summaries[i]))
+		items[i] = diffChunk{label: chunk.label, text: text, tokens: s.countText(text)}
+	}
+	return packChunks(items, limit)
+}
+
+func packChunks(items []diffChunk, limit int) []diffChunk {
+	var chunks []diffChunk
+	var labels []string
+	var current diffChunk
+	flush := func() {
+		if current.text == "" {
+			return
+		}
+		current.label = listFiles(labels)
+		chunks = append(chunks, current)
+		current, labels = diffChunk{}, nil
+	}
+
+	for _, item := range items {
+		if current.tokens+item.tokens > limit {
+			flush()
+		}
+		current.text += item.text
+		current.tokens += item.tokens
+		labels = append(labels, item.label)
+	}
+	flush()
+	return chunks
+}
+
+func joinSummaries(chunks []diffChunk, summaries []string) string {
+	var sb strings.Builder
+	for i, chunk := range chunks {
+		sb.WriteString(fmt.Sprintf("### Part %d: %s\n%s\n\n", i+1, chunk.label, strings.TrimSpace(summaries[i])))
+	}
+	return sb.String()
+}
+
+func (s *diffSummarizer) summarize(parent context.Context, chunks []diffChunk, template string) ([]string, error) {
+	ctx, cancel := s.task.context(parent)
+	defer cancel()
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	if !s.isSilent {
+		s.spinner = newProgressSpinner()
+		s.spinner.Start(s.spinnerLabel(0, len(chunks)))
+		defer s.spinner.Stop()
+	}
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		done     int
+		firstErr error
+	)
+	summaries := make([]string, len(chunks))
+	jobs := make(chan int)
+
+	for w := 0; w < min(summaryWorkers, len(chunks)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				summary, err := s.summarizeChunk(ctx, template, i, len(chunks), chunks[i])
+
+				mu.Lock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = fmt.Errorf("failed to summarize part %d of %d: %w", i+1, len(chunks), err)
+						stop()
+					}
+					mu.Unlock()
+					continue
+				}
+				summaries[i] = summary
+				done++
+				s.chunkDone(i, done, len(chunks), chunks[i])
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for i := range chunks {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	switch {
+	case firstErr != nil:
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		firstErr = s.task.deadlineError()
+	case ctx.Err() != nil:
+		firstErr = fmt.Errorf("%w by user", ai.ErrCancelled)
+	}
+	return summaries, firstErr
+}
+
+func (s *diffSummarizer) summarizeChunk(ctx context.Context, template string, index, total int, chunk diffChunk) (string, error) {
+	if ctx.Err() != nil {
+		return "", fmt.Errorf("%w by user", ai.ErrCancelled)
+	}
+	return s.task.withMessages(ai.UserPrompt(fmt.Sprintf(template, index+1, total, chunk.text))).run(ctx, nil)
+}
+
+func (s *diffSummarizer) spinnerLabel(done, total int) string {
+	return fmt.Sprintf("%s, %d/%d parts summarized", providerLabel(s.cfg.ProviderConfig), done, total)
+}
+
+func (s *diffSummarizer) chunkDone(index, done, total int, chunk diffChunk) {
+	message := fmt.Sprintf("Summarized part %d/%d: %s", index+1, total, chunk.label)
+	if s.isSilent {
+		fmt.Fprintf(os.Stderr, "gct: %s\n", message)
+		return
+	}
+	s.spinner.Start(s.spinnerLabel(done, total))
+	s.spinner.Println(fmt.Sprintf("%s %s", color.GreenString("✓"), message))
+}
+
+func (s *diffSummarizer) progress(message string) {
+	if s.isSilent {
+		fmt.Fprintf(os.Stderr, "gct: %s\n", message)
+		return
+	}
+	fmt.Printf("%s %s\n", color.CyanString("📦"), message)
+}
@@ -0,0 +1,107 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPackChunks(t *testing.T) {
+	items := []diffChunk{
+		{label: "a.go", text: "a", tokens: 4},
+		{label: "b.go", text: "b", tokens: 5},
+		{label: "c.go", text: "c", tokens: 3},
+		{label: "d.go", text: "d", tokens: 12},
+	}
+	chunks := packChunks(items, 10)
+
+	want := []diffChunk{
+		{label: "a.go, b.go", text: "ab", tokens: 9},
+		{label: "c.go", text: "c", tokens: 3},
+		{label: "d.go", text: "d", tokens: 12},
+	}
+	if len(chunks) != len(want) {
+		t.Fatalf("packChunks = %+v, want %+v", chunks, want)
+	}
+	for i := range want {
+		if chunks[i] != want[i] {
+			t.Errorf("chunk %d = %+v, want %+v", i, chunks[i], want[i])
+		}
+	}
+}
+
+func TestChunkFilesSplitsLargeFiles(t *testing.T) {
+	var body strings.Builder
+	for hunk := 0; hunk < 4; hunk++ {
+		fmt.Fprintf(&body, "@@ -%d,0 +%d,40 @@\n", hunk*40+1, hunk*40+1)
+		for line := 0; line < 40; line++ {
+			fmt.Fprintf(&body, "+hunk %d adds line %d of the new code\n", hunk, line)
+		}
+	}
+	small := fileDiff("small.go", "@@ -1 +1 @@\n-a\n+b\n")
+	large := fileDiff("large.go", body.String())
+
+	cfg := fitTestConfig(strings.Repeat("x", 2000))
+	s := &diffSummarizer{cfg: cfg, fitter: newDiffFitter(cfg, "diff", nil, promptOf)}
+	limit := s.chunkLimit(aiChunkSummaryPromptTemplate)
+	chunks := s.chunkFiles(splitDiffFiles(small + large))
+	if len(chunks) < 2 {
+		t.Fatalf("the large file should be split, got %d chunk", len(chunks))
+	}
+
+	var all, labels strings.Builder
+	for _, chunk := range chunks {
+		labels.WriteString(chunk.label + "; ")
+		if chunk.tokens > limit {
+			t.Errorf("%s: %d tokens, over the limit of %d", chunk.label, chunk.tokens, limit)
+		}
+		all.WriteString(chunk.text)
+	}
+	if !strings.Contains(labels.String(), "small.go") || !strings.Contains(labels.String(), "large.go (part 1 of ") {
+		t.Errorf("chunk labels = %q", labels.String())
+	}
+	for hunk := 0; hunk < 4; hunk++ {
+		for line := 0; line < 40; line++ {
+			if !strings.Contains(all.String(), fmt.Sprintf("+hunk %d adds line %d of the new code\n", hunk, line)) {
+				t.Fatalf("hunk %d line %d is missing from the chunks", hunk, line)
+			}
+		}
+	}
+}
+
+func TestSummarizerCutsSummariesThatNeverFit(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	var summary strings.Builder
+	for i := 0; i < 80; i++ {
+		fmt.Fprintf(&summary, "    - changed an important part of the code, item %d\n", i)
+	}
+	script := filepath.Join(t.TempDir(), "script.yaml")
+	if err := os.WriteFile(script, []byte("- response: |\n"+summary.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var files []diffFile
+	for i := 0; i < 3; i++ {
+		body := "@@ -1 +1,80 @@\n" + strings.Repeat(fmt.Sprintf("+file %d gets another line of new code\n", i), 80)
+		files = append(files, splitDiffFiles(fileDiff(fmt.Sprintf("file%d.go", i), body))...)
+	}
+
+	cfg := fitTestConfig(strings.Repeat("x", 4000))
+	cfg.FakeScript = script
+	s := &diffSummarizer{cfg: cfg, task: newAITask("diff", cfg, nil), fitter: newDiffFitter(cfg, "diff", nil, promptOf), isSilent: true}
+	document, err := s.run(context.Background(), files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.fitter.fits(document) {
+		t.Errorf("the summary should be cut to fit the budget of %d tokens", s.fitter.budget)
+	}
+	if !strings.Contains(document, "lines omitted to fit the model's context window]") {
+		t.Errorf("the cut summary should say that lines were left out:\n%s", document)
+	}
+}
@@ -0,0 +1,332 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gct/src/ai"
+	"gct/src/config"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+const (
+	doctorRequestTimeout = 30 * time.Second
+	doctorPrompt         = "Reply with the single word OK."
+)
+
+type doctorReport struct {
+	passed   int
+	warnings int
+	failures int
+}
+
+func (r *doctorReport) section(title string) {
+	yellow := color.New(color.FgYellow).SprintFunc()
+	fmt.Printf("\n%s\n", yellow(strings.ToUpper(title)))
+}
+
+func (r *doctorReport) pass(format string, args ...interface{}) {
+	green := color.New(color.FgGreen).SprintFunc()
+	r.passed++
+	fmt.Printf("  %s %s\n", green("✓"), fmt.Sprintf(format, args...))
+}
+
+func (r *doctorReport) info(format string, args ...interface{}) {
+	faint := color.New(color.Faint).SprintFunc()
+	fmt.Printf("    %s\n", faint(fmt.Sprintf(format, args...)))
+}
+
+func (r *doctorReport) warn(message, fix string) {
+	yellow := color.New(color.FgYellow).SprintFunc()
+	r.warnings++
+	fmt.Printf("  %s %s\n", yellow("!"), message)
+	r.fix(fix)
+}
+
+func (r *doctorReport) fail(message, fix string) {
+	red := color.New(color.FgRed).SprintFunc()
+	r.failures++
+	fmt.Printf("  %s %s\n", red("✗"), message)
+	r.fix(fix)
+}
+
+func (r *doctorReport) fix(fix string) {
+	cyan := color.New(color.FgCyan).SprintFunc()
+	if fix != "" {
+		fmt.Printf("    %s %s\n", cyan("Fix:"), fix)
+	}
+}
+
+func DoctorCommand() {
+	bold := color.New(color.Bold).SprintFunc()
+	green := color.New(color.FgGreen).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+
+	fmt.Printf("%s Checking your configuration...\n", bold("GCT Doctor"))
+
+	report := &doctorReport{}
+
+	report.section("Configuration")
+	cfg := checkConfig(report)
+
+	if cfg != nil {
+		report.section("AI providers")
+		for i, provider := range cfg.ProviderChain() {
+			checkProvider(report, cfg.WithProvider(provider), i)
+		}
+
+		report.section("Guidelines")
+		checkGuidelines(report, "commits", cfg.Commits.Paths)
+		checkGuidelines(report, "changelogs", cfg.Changelogs.Paths)
+
+		report.section("Cache")
+		checkCache(report, cfg)
+	}
+
+	report.section("Git hosting")
+	checkGitHosting(report)
+
+	fmt.Printf("\n%s %s passed, %s, %s\n", bold("Summary:"),
+		green(fmt.Sprintf("%d", report.passed)),
+		yellow(fmt.Sprintf("%d warnings", report.warnings)),
+		red(fmt.Sprintf("%d failures", report.failures)))
+}
+
+func checkConfig(report *doctorReport) *config.Config {
+	path, found := config.FindConfigFile()
+	switch {
+	case !found:
+		report.warn("No gct.yaml found in this directory or its parents, and no global config.",
+			"Run 'gct init' to create one, or set GCT_PROVIDER, GCT_MODEL and GCT_API_KEY.")
+	case filepath.Base(path) == "gct.yaml":
+		report.pass("Using project config %s", path)
+	default:
+		report.pass("Using global config %s", path)
+	}
+
+	if _, err := os.Stat(".env"); err == nil {
+		report.info(".env file in the current directory is loaded into the environment")
+	}
+
+	overrides, unknown := config.EnvOverrides()
+	for _, override := range overrides {
+		report.info("%s overrides '%s'", override.Variable, override.Field)
+	}
+	for _, variable := range unknown {
+		report.warn(fmt.Sprintf("Environment variable %s is not recognised and is ignored.", variable),
+			fmt.Sprintf("Check the spelling of %s or unset it.", variable))
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fix := "Check the file for YAML syntax errors."
+		if strings.Contains(err.Error(), "environment variables") {
+			fix = "Check that numeric and duration GCT_* variables hold valid values."
+		} else if strings.Contains(err.Error(), "no AI provider") {
+			fix = "Run 'gct init', or set the 'provider' field in gct.yaml or GCT_PROVIDER."
+		}
+		report.fail(fmt.Sprintf("Could not load configuration: %v", err), fix)
+		return nil
+	}
+	report.pass("Configuration loaded (provider %s, model %s)", cfg.Provider, cfg.Model)
+	return cfg
+}
+
+func checkProvider(report *doctorReport, cfg *config.Config, index int) {
+	label := cfg.Provider
+	if index > 0 {
+		label = fmt.Sprintf("%s (fallback %d)", cfg.Provider, index)
+	}
+
+	if cfg.Model == "" {
+		report.fail(fmt.Sprintf("%s has no model configured.", label),
+			"Set the 'model' field in gct.yaml or GCT_MODEL. Run 'gct models' to see what is available.")
+		return
+	}
+
+	provider, err := ai.NewProvider(cfg)
+	if err != nil {
+		fix := "Check the provider name and its required fields in gct.yaml, or rerun 'gct init'."
+		if errors.Is(err, config.ErrProjectSecretCommand) {
+			fix = aiErrorHint(err)
+		}
+		report.fail(fmt.Sprintf("%s could not be set up: %v", label, err), fix)
+		return
+	}
+
+	ctx, stop := interruptContext(context.Background())
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, doctorRequestTimeout)
+	defer cancel()
+	ctx = ai.WithRetryPolicy(ctx, ai.RetryPolicy{MaxAttempts: 1})
+
+	if _, ok := provider.(ai.ModelLister); ok {
+		if models, err := ai.ListModels(ctx, cfg); err == nil {
+			if _, found := ai.FindModel(models, cfg.Model); !found {
+				report.warn(fmt.Sprintf("%s does not list model '%s'.", label, cfg.Model),
+					"Run 'gct models' to see the exact model names and update the 'model' field.")
+			}
+		}
+	}
+
+	_, err = provider.Generate(ctx, ai.Request{
+		Messages: []ai.Message{{Role: ai.RoleUser, Content: doctorPrompt}},
+		Options:  ai.GenerationOptions{MaxTokens: 16},
+	})
+	var truncated *ai.TruncatedError
+	if err != nil && !errors.As(err, &truncated) {
+		report.fail(fmt.Sprintf("%s test request failed: %v", label, err), providerErrorFix(cfg, err))
+		return
+	}
+	report.pass("%s answered a test request with model %s", label, cfg.Model)
+}
+
+func providerErrorFix(cfg *config.Config, err error) string {
+	switch {
+	case errors.Is(err, ai.ErrAuth):
+		return "The credentials were rejected. Check the 'api' field in gct.yaml or GCT_API_KEY, and that the key has access to this model."
+	case errors.Is(err, ai.ErrQuotaExhausted), errors.Is(err, ai.ErrRateLimited):
+		return "The provider reports a quota or rate limit. Check your plan and billing, or add a fallback provider."
+	case errors.Is(err, ai.ErrModelNotFound):
+		return fmt.Sprintf("The model '%s' was not found. %s", cfg.Model, ai.ModelNotFoundHint)
+	case errors.Is(err, ai.ErrContentBlocked):
+		return "The provider's content filter blocked the test request. Check 'safety_settings' in gct.yaml."
+	}
+
+	var apiErr *ai.APIError
+	if errors.As(err, &apiErr) {
+		if apiErr.StatusCode == http.StatusNotFound {
+			return "The provider has no API at this address. Check 'endpoint' in gct.yaml, or the base_url of a custom provider."
+		}
+		if apiErr.StatusCode == http.StatusBadRequest {
+			return "The provider rejected the request. Check the model name and any generation settings in gct.yaml."
+		}
+		if apiErr.StatusCode >= http.StatusInternalServerError {
+			return "The provider is having problems. Try again later or add a fallback provider."
+		}
+	}
+
+	if errors.Is(err, ai.ErrTimeout) || errors.Is(err, context.DeadlineExceeded) {
+		return "The request timed out. Check the 'endpoint' field and your network, or raise 'http.timeout'."
+	}
+	var netErr net.Error
+	var opErr *net.OpError
+	if errors.As(err, &netErr) || errors.As(err, &opErr) {
+		if ai.IsLocalProvider(cfg.Provider) {
+			return fmt.Sprintf("Could not reach %s. Make sure the server is running and the 'endpoint' field is correct.", cfg.Provider)
+		}
+		return "Could not reach the provider. Check the 'endpoint' field, your network and the 'http.proxy' setting."
+	}
+	return "Check the provider settings in gct.yaml, or rerun 'gct init'."
+}
+
+func checkGuidelines(report *doctorReport, section string, paths []string) {
+	if len(paths) == 0 {
+		report.info("No %s guides configured", section)
+		return
+	}
+
+	for _, path := range paths {
+		if !strings.HasSuffix(path, ".md") && !strings.HasSuffix(path, ".txt") {
+			report.warn(fmt.Sprintf("%s guide %s is not a .md or .txt file and will be skipped.", section, path),
+				fmt.Sprintf("Convert it to Markdown or plain text, or remove it from '%s.guides'.", section))
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			report.fail(fmt.Sprintf("%s guide %s does not exist.", section, path),
+				fmt.Sprintf("Fix the path in '%s.guides'. Relative paths are resolved from the directory you run gct in.", section))
+			continue
+		}
+		if info.IsDir() {
+			report.fail(fmt.Sprintf("%s guide %s is a directory.", section, path),
+				fmt.Sprintf("List the guide files themselves in '%s.guides'.", section))
+			continue
+		}
+		if _, err := os.ReadFile(path); err != nil {
+			report.fail(fmt.Sprintf("%s guide %s could not be read: %v", section, path, err),
+				fmt.Sprintf("Check the file permissions, e.g. 'chmod u+r %s'.", path))
+			continue
+		}
+		report.pass("%s guide %s is readable", section, path)
+	}
+}
+
+func checkCache(report *doctorReport, cfg *config.Config) {
+	if !cfg.Cache.Enabled {
+		report.info("Response cache is disabled")
+		return
+	}
+
+	cacheDir, err := getCacheDir()
+	if err != nil {
+		report.fail(fmt.Sprintf("Cache directory is unavailable: %v", err),
+			"Run gct inside a git repository, or set 'cache.enabled: false'.")
+		return
+	}
+
+	probe := filepath.Join(cacheDir, ".doctor")
+	if err := os.WriteFile(probe, []byte("ok"), 0644); err != nil {
+		report.fail(fmt.Sprintf("Cache directory %s is not writable: %v", cacheDir, err),
+			fmt.Sprintf("Check the permissions of %s, or set 'cache.enabled: false'.", cacheDir))
+		return
+	}
+	_ = os.Remove(probe)
+	report.pass("Cache directory %s is writable", cacheDir)
+}
+
+func checkGitHosting(report *doctorReport) {
+	if _, err := exec.LookPath("git"); err != nil {
+		report.fail("git is not installed or not in your PATH.", "Install git and make sure it is on your PATH.")
+		return
+	}
+	report.pass("git is installed")
+
+	if _, err := findGitRoot(); err != nil {
+		report.warn("The current directory is not inside a git repository.",
+			"Run gct from inside a repository, or 'git init' to create one.")
+		return
+	}
+
+	output, err := exec.Command("git", "config", "--get", "remote.origin.url").Output()
+	if err != nil {
+		report.warn("The repository has no 'origin' remote, so 'gct ai pr' and 'gct ai issue' will not work.",
+			"Add one with 'git remote add origin <url>'.")
+		return
+	}
+	remoteURL := strings.TrimSpace(string(output))
+
+	platform, cli := hostingPlatform(remoteURL)
+	if platform == "" {
+		report.warn(fmt.Sprintf("Remote %s is not on a supported hosting platform.", remoteURL),
+			"'gct ai pr' and 'gct ai issue' support github.com, gitlab.com, codeberg.org, gitea.com and code.forgejo.org.")
+		return
+	}
+	report.pass("Remote origin is on %s (%s)", platform, remoteURL)
+
+	if _, err := exec.LookPath(cli); err != nil {
+		report.fail(fmt.Sprintf("'%s' (%s CLI) is not installed or not in your PATH.", cli, platform),
+			fmt.Sprintf("Install '%s' to use 'gct ai pr' and 'gct ai issue'.", cli))
+		return
+	}
+
+	if cli == "fj" {
+		report.pass("'%s' is installed", cli)
+		return
+	}
+	if err := exec.Command(cli, "auth", "status").Run(); err != nil {
+		report.fail(fmt.Sprintf("'%s' is installed but not logged in.", cli),
+			fmt.Sprintf("Run '%s auth login'.", cli))
+		return
+	}
+	report.pass("'%s' is installed and logged in", cli)
+}
@@ -7,10 +7,12 @@ import (
 )
 
 type PRDetails struct {
-	Title  string
-	Body   string
-	Author string
-	Diff   string
+	Title   string
+	Body    string
+	Author  string
+	Diff    string
+	BaseRef string
+	HeadRef string
 }
 
 type IssueDetails struct {
@@ -20,6 +22,22 @@ type IssueDetails struct {
 	Labels []string
 }
 
+func (d *PRDetails) GitArgs() []string {
+	if d.BaseRef == "" || d.HeadRef == "" || !isLocalCommit(d.HeadRef) {
+		return nil
+	}
+	for _, base := range []string{"origin/" + d.BaseRef, d.BaseRef} {
+		if isLocalCommit(base) {
+			return []string{base + "..." + d.HeadRef}
+		}
+	}
+	return nil
+}
+
+func isLocalCommit(ref string) bool {
+	return exec.Command("git", "rev-parse", "--verify", "--quiet", ref+"^{commit}").Run() == nil
+}
+
 type GitHostingProvider interface {
 	GetPRDetails(prNumber string) (*PRDetails, error)
 	GetIssueDetails(issueNumber string) (*IssueDetails, error)
@@ -33,13 +51,26 @@ func NewGitHostingProvider() (GitHostingProvider, error) {
 	}
 	remoteURL := strings.TrimSpace(string(output))
 
-	if strings.Contains(remoteURL, "github.com") {
+	switch platform, _ := hostingPlatform(remoteURL); platform {
+	case "GitHub":
 		return NewGitHubProvider()
-	} else if strings.Contains(remoteURL, "gitlab.com") {
+	case "GitLab":
 		return NewGitLabProvider()
-	} else if strings.Contains(remoteURL, "codeberg.org") || strings.Contains(remoteURL, "gitea.com") || strings.Contains(remoteURL, "code.forgejo.org") {
+	case "Forgejo":
 		return NewForgejoProvider()
 	}
 
 	return nil, fmt.Errorf("unsupported git hosting platform for remote: %s", remoteURL)
 }
+
+func hostingPlatform(remoteURL string) (platform string, cli string) {
+	switch {
+	case strings.Contains(remoteURL, "github.com"):
+		return "GitHub", "gh"
+	case strings.Contains(remoteURL, "gitlab.com"):
+		return "GitLab", "glab"
+	case strings.Contains(remoteURL, "codeberg.org") || strings.Contains(remoteURL, "gitea.com") || strings.Contains(remoteURL, "code.forgejo.org"):
+		return "Forgejo", "fj"
+	}
+	return "", ""
+}
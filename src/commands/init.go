@@ -14,6 +14,8 @@ import (
 
 const configFileName = "gct.yaml"
 
+const dotEnvFileName = ".env"
+
 func InitCommand() {
 	cyan := color.New(color.FgCyan).SprintFunc()
 	yellow := color.New(color.FgYellow).SprintFunc()
@@ -59,34 +61,32 @@ func InitCommand() {
 	}
 
 	newConfig := config.Config{
-		Name:               initModel.Name,
-		Provider:           initModel.Provider,
-		Model:              initModel.Model,
-		APIKey:             initModel.APIKey,
-		Endpoint:           initModel.Endpoint,
-		Commits:            config.GuidesConfig{Paths: commitGuidePaths},
-		Changelogs:         config.GuidesConfig{Paths: changelogGuidePaths},
-		GCPProjectID:       initModel.GCPProjectID,
-		GCPRegion:          initModel.GCPRegion,
-		AWSRegion:          initModel.AWSRegion,
-		AWSAccessKeyID:     initModel.AWSAccessKeyID,
-		AWSSecretAccessKey: initModel.AWSSecretAccessKey,
-		AzureResourceName:  initModel.AzureResourceName,
-	}
-
-	yamlData, err := yaml.Marshal(&newConfig)
-	if err != nil {
-		fmt.Printf("%s Failed to create YAML config: %v\n", red("✗"), err)
-		return
+		Name: initModel.Name,
+		ProviderConfig: config.ProviderConfig{
+			Provider:           initModel.Provider,
+			Model:              initModel.Model,
+			APIKey:             initModel.APIKey,
+			APIKeyCommand:      initModel.APIKeyCommand,
+			Endpoint:           initModel.Endpoint,
+			GCPProjectID:       initModel.GCPProjectID,
+			GCPRegion:          initModel.GCPRegion,
+			AWSRegion:          initModel.AWSRegion,
+			AWSProfile:         initModel.AWSProfile,
+			AWSAccessKeyID:     initModel.AWSAccessKeyID,
+			AWSSecretAccessKey: initModel.AWSSecretAccessKey,
+			AzureResourceName:  initModel.AzureResourceName,
+		},
+		Commits:    config.GuidesConfig{Paths: commitGuidePaths},
+		Changelogs: config.GuidesConfig{Paths: changelogGuidePaths},
 	}
 
-	err = os.WriteFile(configFileName, yamlData, 0644)
-	if err != nil {
-		fmt.Printf("%s Failed to write %s: %v\n", red("✗"), configFileName, err)
+	if err := writeConfigFile(newConfig); err != nil {
+		fmt.Printf("%s %v\n", red("✗"), err)
 		return
 	}
 
 	fmt.Printf("\n%s Config file '%s' created successfully!\n", green("✓"), configFileName)
+	printSecretStorageNote(newConfig)
 
 	err = addPathToGitignore(configFileName, "/.gct/")
 	if err != nil {
@@ -99,6 +99,72 @@ func InitCommand() {
 	fmt.Printf("%s You can now run %s to generate commit messages.\n", cyan("›"), yellow("gct ai commit"))
 }
 
+func writeConfigFile(cfg config.Config) error {
+	if cfg.APIKeyCommand != "" {
+		if err := writeDotEnvCommand(cfg.APIKeyCommand); err != nil {
+			return err
+		}
+		cfg.APIKeyCommand = ""
+	}
+
+	yamlData, err := yaml.Marshal(&cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create YAML config: %w", err)
+	}
+
+	perm := os.FileMode(0644)
+	if cfg.HasInlineSecrets() {
+		perm = 0600
+	}
+	if err := os.WriteFile(configFileName, yamlData, perm); err != nil {
+		return fmt.Errorf("failed to write %s: %w", configFileName, err)
+	}
+	if err := os.Chmod(configFileName, perm); err != nil {
+		return fmt.Errorf("failed to set permissions on %s: %w", configFileName, err)
+	}
+	return nil
+}
+
+func writeDotEnvCommand(command string) error {
+	green := color.New(color.FgGreen).SprintFunc()
+
+	file, err := os.OpenFile(dotEnvFileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", dotEnvFileName, err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	prefix := ""
+	if existing, err := os.ReadFile(dotEnvFileName); err == nil && len(existing) > 0 && !strings.HasSuffix(string(existing), "\n") {
+		prefix = "\n"
+	}
+	quoted := "'" + command + "'"
+	if strings.Contains(command, "'") {
+		quoted = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`).Replace(command) + `"`
+	}
+	if _, err := fmt.Fprintf(file, "%sGCT_API_KEY_COMMAND=%s\n", prefix, quoted); err != nil {
+		return fmt.Errorf("failed to write %s: %w", dotEnvFileName, err)
+	}
+	if err := addPathToGitignore(dotEnvFileName); err != nil {
+		return err
+	}
+	fmt.Printf("%s The API key command was saved as GCT_API_KEY_COMMAND in '%s', because a project gct.yaml cannot run commands.\n",
+		green("✓"), dotEnvFileName)
+	return nil
+}
+
+func printSecretStorageNote(cfg config.Config) {
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	if !cfg.HasInlineSecrets() {
+		return
+	}
+	fmt.Printf("%s '%s' holds your credentials in plain text, so only your user can read it.\n", yellow("Note:"), configFileName)
+	fmt.Printf("%s Use ${ENV_VAR}, file:<path> or api_key_command to keep keys out of the file.\n", yellow("Hint:"))
+}
+
 func addPathToGitignore(pathsToAdd ...string) error {
 	const gitignoreFileName = ".gitignore"
 
@@ -8,7 +8,6 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/fatih/color"
-	"gopkg.in/yaml.v3"
 )
 
 func InitPresetCommand() {
@@ -55,34 +54,32 @@ func InitPresetCommand() {
 	}
 
 	newConfig := config.Config{
-		Name:               initModel.Name,
-		Provider:           initModel.Provider,
-		Model:              initModel.Model,
-		APIKey:             initModel.APIKey,
-		Endpoint:           initModel.Endpoint,
-		Commits:            config.GuidesConfig{Paths: commitGuidePaths},
-		Changelogs:         config.GuidesConfig{Paths: changelogGuidePaths},
-		GCPProjectID:       initModel.GCPProjectID,
-		GCPRegion:          initModel.GCPRegion,
-		AWSRegion:          initModel.AWSRegion,
-		AWSAccessKeyID:     initModel.AWSAccessKeyID,
-		AWSSecretAccessKey: initModel.AWSSecretAccessKey,
-		AzureResourceName:  initModel.AzureResourceName,
+		Name: initModel.Name,
+		ProviderConfig: config.ProviderConfig{
+			Provider:           initModel.Provider,
+			Model:              initModel.Model,
+			APIKey:             initModel.APIKey,
+			APIKeyCommand:      initModel.APIKeyCommand,
+			Endpoint:           initModel.Endpoint,
+			GCPProjectID:       initModel.GCPProjectID,
+			GCPRegion:          initModel.GCPRegion,
+			AWSRegion:          initModel.AWSRegion,
+			AWSProfile:         initModel.AWSProfile,
+			AWSAccessKeyID:     initModel.AWSAccessKeyID,
+			AWSSecretAccessKey: initModel.AWSSecretAccessKey,
+			AzureResourceName:  initModel.AzureResourceName,
+		},
+		Commits:    config.GuidesConfig{Paths: commitGuidePaths},
+		Changelogs: config.GuidesConfig{Paths: changelogGuidePaths},
 	}
 
-	yamlData, err := yaml.Marshal(&newConfig)
-	if err != nil {
-		fmt.Printf("%s Failed to create YAML config: %v\n", red("✗"), err)
-		return
-	}
-
-	err = os.WriteFile(configFileName, yamlData, 0644)
-	if err != nil {
-		fmt.Printf("%s Failed to write %s: %v\n", red("✗"), configFileName, err)
+	if err := writeConfigFile(newConfig); err != nil {
+		fmt.Printf("%s %v\n", red("✗"), err)
 		return
 	}
 
 	fmt.Printf("\n%s Config file '%s' created successfully!\n", green("✓"), configFileName)
+	printSecretStorageNote(newConfig)
 	err = addPathToGitignore(configFileName)
 	if err != nil {
 		fmt.Printf("%s Could not automatically update .gitignore: %v\n", yellow("Warning:"), err)
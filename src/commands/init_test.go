@@ -0,0 +1,23 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/joho/godotenv"
+)
+
+func TestWriteDotEnvCommand(t *testing.T) {
+	for _, command := range []string{"pass show openai", `op read 'op://Private/OpenAI/key' | tr -d "\n" # $HOME \ok`} {
+		t.Chdir(t.TempDir())
+		if err := writeDotEnvCommand(command); err != nil {
+			t.Fatal(err)
+		}
+		values, err := godotenv.Read(dotEnvFileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if values["GCT_API_KEY_COMMAND"] != command {
+			t.Errorf("GCT_API_KEY_COMMAND = %q, want %q", values["GCT_API_KEY_COMMAND"], command)
+		}
+	}
+}
@@ -1,9 +1,12 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"gct/src/ai"
+	"gct/src/config"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
@@ -17,11 +20,12 @@ const (
 	stateInitGCPProjectID
 	stateInitGCPRegion
 	stateInitAWSRegion
+	stateInitAWSProfile
 	stateInitAWSAccessKeyID
 	stateInitAWSSecretAccessKey
 	stateInitAzureResourceName
-	stateInitModel
 	stateInitAPIKey
+	stateInitModel
 	stateInitCommitGuides
 	stateInitChangelogGuides
 	stateSubmit
@@ -45,6 +49,7 @@ type InitTUIModel struct {
 	Endpoint        string
 	Model           string
 	APIKey          string
+	APIKeyCommand   string
 	CommitGuides    string
 	ChangelogGuides string
 
@@ -52,17 +57,23 @@ type InitTUIModel struct {
 	GCPRegion    string
 
 	AWSRegion          string
+	AWSProfile         string
 	AWSAccessKeyID     string
 	AWSSecretAccessKey string
 
 	AzureResourceName string
 
+	models        []ai.ModelInfo
+	modelsErr     error
+	modelsLoading bool
+	modelCursor   int
+
 	quitting  bool
 	submitted bool
 }
 
 func NewInitTUIModel() InitTUIModel {
-	inputs := make([]textinput.Model, 12)
+	inputs := make([]textinput.Model, 13)
 
 	inputs[0] = textinput.New()
 	inputs[0].Placeholder = "My Awesome Project"
@@ -76,10 +87,10 @@ func NewInitTUIModel() InitTUIModel {
 	inputs[1].Width = 50
 
 	inputs[2] = textinput.New()
-	inputs[2].Placeholder = "sk-..."
+	inputs[2].Placeholder = "sk-..., ${OPENAI_API_KEY}, file:~/.keys/openai or !pass show openai"
 	inputs[2].EchoMode = textinput.EchoPassword
 	inputs[2].EchoCharacter = '•'
-	inputs[2].CharLimit = 128
+	inputs[2].CharLimit = 256
 	inputs[2].Width = 50
 
 	inputs[3] = textinput.New()
@@ -113,17 +124,17 @@ func NewInitTUIModel() InitTUIModel {
 	inputs[8].Width = 50
 
 	inputs[9] = textinput.New()
-	inputs[9].Placeholder = "AKIA..."
+	inputs[9].Placeholder = "AKIA... (optional)"
 	inputs[9].EchoMode = textinput.EchoPassword
 	inputs[9].EchoCharacter = '•'
 	inputs[9].CharLimit = 128
 	inputs[9].Width = 50
 
 	inputs[10] = textinput.New()
-	inputs[10].Placeholder = "Your very long secret key"
+	inputs[10].Placeholder = "Your very long secret key, ${AWS_SECRET_ACCESS_KEY} or file:<path> (optional)"
 	inputs[10].EchoMode = textinput.EchoPassword
 	inputs[10].EchoCharacter = '•'
-	inputs[10].CharLimit = 128
+	inputs[10].CharLimit = 256
 	inputs[10].Width = 50
 
 	inputs[11] = textinput.New()
@@ -131,12 +142,88 @@ func NewInitTUIModel() InitTUIModel {
 	inputs[11].CharLimit = 100
 	inputs[11].Width = 50
 
+	inputs[12] = textinput.New()
+	inputs[12].Placeholder = "default (optional, leave empty to enter access keys)"
+	inputs[12].CharLimit = 100
+	inputs[12].Width = 50
+
 	return InitTUIModel{
 		currentState: stateInitName,
 		inputs:       inputs,
 	}
 }
 
+const initModelListTimeout = 15 * time.Second
+const initModelListRows = 10
+
+type modelsMsg struct {
+	models []ai.ModelInfo
+	err    error
+}
+
+func fetchModels(cfg *config.Config) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), initModelListTimeout)
+		defer cancel()
+		ctx = ai.WithRetryPolicy(ctx, ai.RetryPolicy{MaxAttempts: 1})
+
+		models, err := ai.ListModels(ctx, cfg)
+		return modelsMsg{models: models, err: err}
+	}
+}
+
+func (m InitTUIModel) providerConfig() *config.Config {
+	return &config.Config{
+		ProviderConfig: config.ProviderConfig{
+			Provider:           m.Provider,
+			APIKey:             m.APIKey,
+			APIKeyCommand:      m.APIKeyCommand,
+			Endpoint:           m.Endpoint,
+			GCPProjectID:       m.GCPProjectID,
+			GCPRegion:          m.GCPRegion,
+			AWSRegion:          m.AWSRegion,
+			AWSProfile:         m.AWSProfile,
+			AWSAccessKeyID:     m.AWSAccessKeyID,
+			AWSSecretAccessKey: m.AWSSecretAccessKey,
+			AzureResourceName:  m.AzureResourceName,
+		},
+	}
+}
+
+func splitAPIKeyInput(value string) (apiKey, command string) {
+	value = strings.TrimSpace(value)
+	if rest, ok := strings.CutPrefix(value, "!"); ok {
+		return "", strings.TrimSpace(rest)
+	}
+	return value, ""
+}
+
+func secretEchoMode(value string) textinput.EchoMode {
+	if config.IsSecretReference(value) || strings.HasPrefix(strings.TrimSpace(value), "!") {
+		return textinput.EchoNormal
+	}
+	return textinput.EchoPassword
+}
+
+func secretSummary(value string) string {
+	if config.IsSecretReference(value) {
+		return value
+	}
+	return "[hidden]"
+}
+
+func (m InitTUIModel) enterModelStep() (InitTUIModel, tea.Cmd) {
+	m.currentState = stateInitModel
+	m.inputs[1].Focus()
+	m.modelsLoading = true
+	m.modelCursor = 0
+	return m, tea.Batch(textinput.Blink, fetchModels(m.providerConfig()))
+}
+
+func (m InitTUIModel) filteredModels() []ai.ModelInfo {
+	return ai.FilterModels(m.models, m.inputs[1].Value())
+}
+
 func (m InitTUIModel) Init() tea.Cmd {
 	return textinput.Blink
 }
@@ -170,6 +257,14 @@ func (m InitTUIModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				case "openaicompatible":
 					m.currentState = stateInitEndpoint
 					m.inputs[4].Focus()
+				case "ollama":
+					m.currentState = stateInitEndpoint
+					m.inputs[4].Placeholder = "http://localhost:11434 (leave empty for default)"
+					m.inputs[4].Focus()
+				case "llama.cpp":
+					m.currentState = stateInitEndpoint
+					m.inputs[4].Placeholder = "http://localhost:8080/v1 (leave empty for default)"
+					m.inputs[4].Focus()
 				case "googlevertexai", "vertexai", "vertex":
 					m.currentState = stateInitGCPProjectID
 					m.inputs[6].Focus()
@@ -180,16 +275,19 @@ func (m InitTUIModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.currentState = stateInitAzureResourceName
 					m.inputs[11].Focus()
 				default:
-					m.currentState = stateInitModel
-					m.inputs[1].Focus()
+					m.currentState = stateInitAPIKey
+					m.inputs[2].Focus()
 				}
 				return m, textinput.Blink
 
 			case stateInitEndpoint:
 				m.Endpoint = m.inputs[4].Value()
-				m.currentState = stateInitModel
 				m.inputs[4].Blur()
-				m.inputs[1].Focus()
+				if ai.IsLocalProvider(m.Provider) {
+					return m.enterModelStep()
+				}
+				m.currentState = stateInitAPIKey
+				m.inputs[2].Focus()
 				return m, textinput.Blink
 
 			case stateInitGCPProjectID:
@@ -201,16 +299,27 @@ func (m InitTUIModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 			case stateInitGCPRegion:
 				m.GCPRegion = m.inputs[7].Value()
-				m.currentState = stateInitModel
+				m.currentState = stateInitAPIKey
 				m.inputs[7].Blur()
-				m.inputs[1].Focus()
+				m.inputs[2].Focus()
 				return m, textinput.Blink
 
 			case stateInitAWSRegion:
 				m.AWSRegion = m.inputs[8].Value()
-				m.currentState = stateInitAWSAccessKeyID
+				m.currentState = stateInitAWSProfile
 				m.inputs[8].Blur()
-				m.inputs[9].Focus()
+				m.inputs[12].Focus()
+				return m, textinput.Blink
+
+			case stateInitAWSProfile:
+				m.AWSProfile = m.inputs[12].Value()
+				m.inputs[12].Blur()
+				if m.AWSProfile != "" {
+					return m.enterModelStep()
+				} else {
+					m.currentState = stateInitAWSAccessKeyID
+					m.inputs[9].Focus()
+				}
 				return m, textinput.Blink
 
 			case stateInitAWSAccessKeyID:
@@ -222,35 +331,31 @@ func (m InitTUIModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 			case stateInitAWSSecretAccessKey:
 				m.AWSSecretAccessKey = m.inputs[10].Value()
-				m.currentState = stateInitModel
 				m.inputs[10].Blur()
-				m.inputs[1].Focus()
-				return m, textinput.Blink
+				return m.enterModelStep()
 
 			case stateInitAzureResourceName:
 				m.AzureResourceName = m.inputs[11].Value()
-				m.currentState = stateInitModel
+				m.currentState = stateInitAPIKey
 				m.inputs[11].Blur()
-				m.inputs[1].Focus()
+				m.inputs[2].Focus()
 				return m, textinput.Blink
 
+			case stateInitAPIKey:
+				m.APIKey, m.APIKeyCommand = splitAPIKeyInput(m.inputs[2].Value())
+				m.inputs[2].Blur()
+				return m.enterModelStep()
+
 			case stateInitModel:
-				m.Model = m.inputs[1].Value()
-				if providerID == "amazonbedrock" || providerID == "bedrock" || providerID == "amazon" || providerID == "aws" {
-					m.currentState = stateInitCommitGuides
-					m.inputs[1].Blur()
-					m.inputs[3].Focus()
-				} else {
-					m.currentState = stateInitAPIKey
-					m.inputs[1].Blur()
-					m.inputs[2].Focus()
+				if m.modelsLoading {
+					return m, nil
+				}
+				m.Model = strings.TrimSpace(m.inputs[1].Value())
+				if matches := m.filteredModels(); len(matches) > 0 {
+					m.Model = matches[m.modelCursor].ID
 				}
-				return m, textinput.Blink
-
-			case stateInitAPIKey:
-				m.APIKey = m.inputs[2].Value()
 				m.currentState = stateInitCommitGuides
-				m.inputs[2].Blur()
+				m.inputs[1].Blur()
 				m.inputs[3].Focus()
 				return m, textinput.Blink
 
@@ -275,13 +380,35 @@ func (m InitTUIModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.providerCursor--
 				}
 			}
+			if m.currentState == stateInitModel {
+				if m.modelCursor > 0 {
+					m.modelCursor--
+				}
+				return m, nil
+			}
 		case tea.KeyDown, tea.KeyTab:
 			if m.currentState == stateInitProvider {
 				if m.providerCursor < len(ai.SupportedProviders)-1 {
 					m.providerCursor++
 				}
 			}
+			if m.currentState == stateInitModel {
+				if m.modelCursor < len(m.filteredModels())-1 {
+					m.modelCursor++
+				}
+				return m, nil
+			}
+		}
+
+	case modelsMsg:
+		m.modelsLoading = false
+		m.models = msg.models
+		m.modelsErr = msg.err
+		if len(m.models) > 0 {
+			m.inputs[1].Prompt = "Search: "
+			m.inputs[1].Placeholder = "type part of a model name"
 		}
+		return m, nil
 
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
@@ -292,8 +419,12 @@ func (m InitTUIModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.inputs[0], cmd = m.inputs[0].Update(msg)
 	case stateInitModel:
 		m.inputs[1], cmd = m.inputs[1].Update(msg)
+		if m.modelCursor >= len(m.filteredModels()) {
+			m.modelCursor = 0
+		}
 	case stateInitAPIKey:
 		m.inputs[2], cmd = m.inputs[2].Update(msg)
+		m.inputs[2].EchoMode = secretEchoMode(m.inputs[2].Value())
 	case stateInitCommitGuides:
 		m.inputs[3], cmd = m.inputs[3].Update(msg)
 	case stateInitEndpoint:
@@ -310,8 +441,11 @@ func (m InitTUIModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.inputs[9], cmd = m.inputs[9].Update(msg)
 	case stateInitAWSSecretAccessKey:
 		m.inputs[10], cmd = m.inputs[10].Update(msg)
+		m.inputs[10].EchoMode = secretEchoMode(m.inputs[10].Value())
 	case stateInitAzureResourceName:
 		m.inputs[11], cmd = m.inputs[11].Update(msg)
+	case stateInitAWSProfile:
+		m.inputs[12], cmd = m.inputs[12].Update(msg)
 	}
 
 	cmds = append(cmds, cmd)
@@ -376,6 +510,13 @@ func (m InitTUIModel) View() string {
 	} else if m.currentState == stateInitAWSRegion {
 		s.WriteString("\nAWS Region:\n" + m.inputs[8].View() + "\n")
 	}
+	if m.currentState > stateInitAWSProfile {
+		if m.AWSProfile != "" {
+			s.WriteString(fmt.Sprintf("%s AWS Profile: %s\n", checkmarkStyleInit.Render("✓"), m.AWSProfile))
+		}
+	} else if m.currentState == stateInitAWSProfile {
+		s.WriteString("\nAWS Profile (from ~/.aws/config, works with SSO):\n" + m.inputs[12].View() + "\n")
+	}
 	if m.currentState > stateInitAWSAccessKeyID {
 		if m.AWSAccessKeyID != "" {
 			s.WriteString(fmt.Sprintf("%s AWS Access Key ID: %s\n", checkmarkStyleInit.Render("✓"), "[hidden]"))
@@ -385,10 +526,11 @@ func (m InitTUIModel) View() string {
 	}
 	if m.currentState > stateInitAWSSecretAccessKey {
 		if m.AWSSecretAccessKey != "" {
-			s.WriteString(fmt.Sprintf("%s AWS Secret Access Key: %s\n", checkmarkStyleInit.Render("✓"), "[hidden]"))
+			s.WriteString(fmt.Sprintf("%s AWS Secret Access Key: %s\n", checkmarkStyleInit.Render("✓"), secretSummary(m.AWSSecretAccessKey)))
 		}
 	} else if m.currentState == stateInitAWSSecretAccessKey {
 		s.WriteString("\nAWS Secret Access Key:\n" + m.inputs[10].View() + "\n")
+		s.WriteString(promptStyleInit.Render("Enter ${ENV_VAR} or file:<path> to keep the key out of gct.yaml.") + "\n")
 	}
 
 	if m.currentState > stateInitAzureResourceName {
@@ -399,6 +541,21 @@ func (m InitTUIModel) View() string {
 		s.WriteString("\nAzure Resource Name:\n" + m.inputs[11].View() + "\n")
 	}
 
+	if m.currentState > stateInitAPIKey {
+		if m.APIKeyCommand != "" {
+			s.WriteString(fmt.Sprintf("%s API Key: from '%s'\n", checkmarkStyleInit.Render("✓"), m.APIKeyCommand))
+		} else if m.APIKey != "" {
+			s.WriteString(fmt.Sprintf("%s API Key: %s\n", checkmarkStyleInit.Render("✓"), secretSummary(m.APIKey)))
+		}
+	} else if m.currentState == stateInitAPIKey {
+		apiKeyPrompt := "\nAPI Key:\n"
+		if m.Provider == "Google Vertex AI" {
+			apiKeyPrompt = "\nAccess Token (optional, leave empty to use gcloud or a service account):\n"
+		}
+		s.WriteString(apiKeyPrompt + m.inputs[2].View() + "\n")
+		s.WriteString(promptStyleInit.Render("To keep the key out of gct.yaml, enter ${ENV_VAR}, file:<path>, or !<command> to run a command such as 'pass show openai'.") + "\n")
+	}
+
 	if m.currentState > stateInitModel {
 		s.WriteString(fmt.Sprintf("%s Model/Deployment: %s\n", checkmarkStyleInit.Render("✓"), m.Model))
 	} else if m.currentState == stateInitModel {
@@ -406,15 +563,43 @@ func (m InitTUIModel) View() string {
 		if m.Provider == "Azure OpenAI" {
 			modelPrompt = "\nAzure Deployment Name (this is your 'model'):\n"
 		}
-		s.WriteString(modelPrompt + m.inputs[1].View() + "\n")
-	}
-
-	if m.currentState > stateInitAPIKey {
-		if m.APIKey != "" {
-			s.WriteString(fmt.Sprintf("%s API Key: %s\n", checkmarkStyleInit.Render("✓"), "[hidden]"))
+		switch {
+		case m.modelsLoading:
+			s.WriteString("\nLooking for available models...\n")
+		case len(m.models) > 0:
+			matches := m.filteredModels()
+			s.WriteString(fmt.Sprintf("\nSelect a model (type to search, use ↑/↓, %d available):\n", len(m.models)))
+			s.WriteString(m.inputs[1].View() + "\n")
+
+			start := 0
+			if m.modelCursor >= initModelListRows {
+				start = m.modelCursor - initModelListRows + 1
+			}
+			end := min(start+initModelListRows, len(matches))
+			for i := start; i < end; i++ {
+				cursor := "  "
+				if m.modelCursor == i {
+					cursor = selectedStyleInit.Render("> ")
+				}
+				details := ""
+				if matches[i].ContextWindow > 0 {
+					details = promptStyleInit.Render(fmt.Sprintf("  %s context", formatContextWindow(matches[i].ContextWindow)))
+				}
+				s.WriteString(fmt.Sprintf("%s%s%s\n", cursor, matches[i].ID, details))
+			}
+			if len(matches) == 0 {
+				s.WriteString(promptStyleInit.Render("  No match; press Enter to use the name as typed.") + "\n")
+			} else if len(matches) > end {
+				s.WriteString(promptStyleInit.Render(fmt.Sprintf("  ... %d more", len(matches)-end)) + "\n")
+			}
+		default:
+			if m.modelsErr != nil {
+				s.WriteString(promptStyleInit.Render(fmt.Sprintf("\nCould not list models: %v", m.modelsErr)) + "\n")
+			} else if ai.IsLocalProvider(m.Provider) {
+				s.WriteString(promptStyleInit.Render("\nNo installed models were found on the server.") + "\n")
+			}
+			s.WriteString(modelPrompt + m.inputs[1].View() + "\n")
 		}
-	} else if m.currentState == stateInitAPIKey {
-		s.WriteString("\nAPI Key:\n" + m.inputs[2].View() + "\n")
 	}
 
 	if m.currentState > stateInitCommitGuides {
@@ -0,0 +1,113 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"gct/src/ai"
+	"gct/src/config"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+const modelListTimeout = 30 * time.Second
+
+func ModelsCommand(args []string) {
+	red := color.New(color.FgRed).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+	cyan := color.New(color.FgCyan).SprintFunc()
+	green := color.New(color.FgGreen).SprintFunc()
+	bold := color.New(color.Bold).SprintFunc()
+	faint := color.New(color.Faint).SprintFunc()
+
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") {
+			fmt.Println(yellow("Usage: gct models [search]"))
+			return
+		}
+	}
+	query := strings.Join(args, " ")
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Printf("%s %v\n", red("Error:"), err)
+		return
+	}
+
+	fmt.Printf("%s Fetching models from %s...\n", cyan("🔍"), cfg.Provider)
+
+	ctx, stop := interruptContext(context.Background())
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, modelListTimeout)
+	defer cancel()
+	ctx = ai.WithRetryPolicy(ctx, ai.NewRetryPolicy(cfg.Retry))
+
+	models, err := ai.ListModels(ctx, cfg)
+	if err != nil {
+		fmt.Printf("%s %v\n", red("Error:"), err)
+		return
+	}
+
+	shown := ai.FilterModels(models, query)
+	if query != "" {
+		fmt.Printf("\n%s %d of %d models from %s match '%s'\n", bold("GCT"), len(shown), len(models), cfg.Provider, query)
+	} else {
+		fmt.Printf("\n%s %d models available from %s\n", bold("GCT"), len(models), cfg.Provider)
+	}
+
+	if len(shown) > 0 {
+		fmt.Printf("\n  %-48s %10s  %s\n", yellow("MODEL"), yellow("CONTEXT"), yellow("PRICE (IN/OUT PER 1M)"))
+	}
+	for _, model := range shown {
+		marker := "  "
+		id := model.ID
+		if strings.EqualFold(model.ID, cfg.Model) {
+			marker = green("* ")
+			id = green(model.ID)
+		}
+
+		spec := ai.LookupModel(cfg, config.ProviderConfig{Provider: cfg.Provider, Model: model.ID})
+		if model.ContextWindow == 0 {
+			model.ContextWindow = spec.ContextWindow
+		}
+		if model.InputPrice == 0 && model.OutputPrice == 0 {
+			model.InputPrice, model.OutputPrice = spec.Pricing.Input, spec.Pricing.Output
+		}
+
+		context := faint("-")
+		if model.ContextWindow > 0 {
+			context = formatContextWindow(model.ContextWindow)
+		}
+		price := faint("-")
+		if model.InputPrice > 0 || model.OutputPrice > 0 {
+			price = fmt.Sprintf("$%.2f / $%.2f", model.InputPrice, model.OutputPrice)
+		}
+
+		fmt.Printf("%s%-48s %10s  %s\n", marker, id, context, price)
+		if model.Name != "" && model.Name != model.ID {
+			fmt.Printf("    %s\n", faint(model.Name))
+		}
+	}
+
+	if cfg.Model == "" {
+		return
+	}
+	if _, found := ai.FindModel(models, cfg.Model); found {
+		fmt.Printf("\n%s Configured model '%s' is available.\n", green("✓"), cfg.Model)
+		return
+	}
+	fmt.Printf("\n%s Configured model '%s' was not found for %s. Check the 'model' field in gct.yaml.\n",
+		yellow("!"), cfg.Model, cfg.Provider)
+}
+
+func formatContextWindow(tokens int) string {
+	switch {
+	case tokens >= 1_000_000:
+		return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.2f", float64(tokens)/1_000_000), "0"), ".") + "M"
+	case tokens >= 1_000:
+		return fmt.Sprintf("%dK", tokens/1_000)
+	default:
+		return fmt.Sprintf("%d", tokens)
+	}
+}
@@ -16,7 +16,7 @@ func NewGitHubProvider() (*GitHubProvider, error) {
 }
 
 func (p *GitHubProvider) GetPRDetails(prNumber string) (*PRDetails, error) {
-	cmd := exec.Command("gh", "pr", "view", prNumber, "--json", "title,body,author,diff")
+	cmd := exec.Command("gh", "pr", "view", prNumber, "--json", "title,body,author,diff,baseRefName,headRefOid")
 	output, err := cmd.Output()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get PR details from gh CLI: %w. Is the PR number correct?", err)
@@ -28,7 +28,9 @@ func (p *GitHubProvider) GetPRDetails(prNumber string) (*PRDetails, error) {
 		Author struct {
 			Login string `json:"login"`
 		} `json:"author"`
-		Diff string `json:"diff"`
+		Diff        string `json:"diff"`
+		BaseRefName string `json:"baseRefName"`
+		HeadRefOid  string `json:"headRefOid"`
 	}
 
 	if err := json.Unmarshal(output, &ghPR); err != nil {
@@ -36,10 +38,12 @@ func (p *GitHubProvider) GetPRDetails(prNumber string) (*PRDetails, error) {
 	}
 
 	return &PRDetails{
-		Title:  ghPR.Title,
-		Body:   ghPR.Body,
-		Author: ghPR.Author.Login,
-		Diff:   ghPR.Diff,
+		Title:   ghPR.Title,
+		Body:    ghPR.Body,
+		Author:  ghPR.Author.Login,
+		Diff:    ghPR.Diff,
+		BaseRef: ghPR.BaseRefName,
+		HeadRef: ghPR.HeadRefOid,
 	}, nil
 }
 
@@ -28,6 +28,10 @@ func (p *GitLabProvider) GetPRDetails(prNumber string) (*PRDetails, error) {
 		Author struct {
 			Username string `json:"username"`
 		} `json:"author"`
+		DiffRefs struct {
+			BaseSHA string `json:"base_sha"`
+			HeadSHA string `json:"head_sha"`
+		} `json:"diff_refs"`
 	}
 
 	if err := json.Unmarshal(output, &glabMR); err != nil {
@@ -44,10 +48,12 @@ func (p *GitLabProvider) GetPRDetails(prNumber string) (*PRDetails, error) {
 	}
 
 	return &PRDetails{
-		Title:  glabMR.Title,
-		Body:   glabMR.Body,
-		Author: glabMR.Author.Username,
-		Diff:   diffStr,
+		Title:   glabMR.Title,
+		Body:    glabMR.Body,
+		Author:  glabMR.Author.Username,
+		Diff:    diffStr,
+		BaseRef: glabMR.DiffRefs.BaseSHA,
+		HeadRef: glabMR.DiffRefs.HeadSHA,
 	}, nil
 }
 
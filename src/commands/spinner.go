@@ -0,0 +1,120 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
+)
+
+const spinnerInterval = 100 * time.Millisecond
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+type progressSpinner struct {
+	mu       sync.Mutex
+	label    string
+	started  time.Time
+	frame    int
+	running  bool
+	animated bool
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func newProgressSpinner() *progressSpinner {
+	return &progressSpinner{
+		animated: isatty.IsTerminal(os.Stdout.Fd()) || isatty.IsCygwinTerminal(os.Stdout.Fd()),
+	}
+}
+
+func spinnerFrame(frame int) string {
+	return spinnerFrames[frame%len(spinnerFrames)]
+}
+
+func formatElapsed(elapsed time.Duration) string {
+	if elapsed < time.Minute {
+		return fmt.Sprintf("%ds", int(elapsed.Seconds()))
+	}
+	return fmt.Sprintf("%dm%02ds", int(elapsed.Minutes()), int(elapsed.Seconds())%60)
+}
+
+func (s *progressSpinner) Start(label string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.label = label
+	if s.running {
+		return
+	}
+	s.running = true
+	s.started = time.Now()
+
+	if !s.animated {
+		fmt.Println(color.CyanString("[Thinking] %s...", label))
+		return
+	}
+
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	go s.animate(s.stop, s.done)
+}
+
+func (s *progressSpinner) animate(stop, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(spinnerInterval)
+	defer ticker.Stop()
+
+	for {
+		s.mu.Lock()
+		s.draw()
+		s.frame++
+		s.mu.Unlock()
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *progressSpinner) draw() {
+	cyan := color.New(color.FgCyan).SprintFunc()
+	faint := color.New(color.Faint).SprintFunc()
+	fmt.Printf("\r\033[K%s Thinking with %s %s", cyan(spinnerFrame(s.frame)), s.label,
+		faint("("+formatElapsed(time.Since(s.started))+", Ctrl+C to cancel)"))
+}
+
+func (s *progressSpinner) Println(message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running && s.animated {
+		fmt.Print("\r\033[K")
+		fmt.Println(message)
+		s.draw()
+		return
+	}
+	fmt.Printf("\n%s\n", message)
+}
+
+func (s *progressSpinner) Stop() {
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = false
+	stop, done := s.stop, s.done
+	s.mu.Unlock()
+
+	if s.animated {
+		close(stop)
+		<-done
+		fmt.Print("\r\033[K")
+	}
+}
@@ -22,6 +22,13 @@ func PrintUsage() {
 	fmt.Printf("    %s %s\n", faint("  └─"), green("model"))
 	fmt.Printf("  %-18s          Show GCT version information\n", green("version"))
 	fmt.Printf("  %-18s          Display details and information about GCT\n", green("about"))
+	fmt.Printf("  %-18s          Summarize recorded AI token usage and estimated costs\n", green("usage"))
+	fmt.Printf("    %s %s\n", faint("└─"), "Limit the report to the last N days (default 30)")
+	fmt.Printf("    %s %s\n", faint("  └─"), green("--days <n>"))
+	fmt.Printf("  %-18s          List the models your provider offers and check the configured one\n", green("models"))
+	fmt.Printf("    %s %s\n", faint("└─"), "Only show models whose name contains the search text")
+	fmt.Printf("    %s %s\n", faint("  └─"), green("<search>"))
+	fmt.Printf("  %-18s          Check configuration, credentials, guides, cache and git hosting\n", green("doctor"))
 	fmt.Printf("  %-18s          Create a CI workflow for automated changelogs\n", green("setup <github|gitlab>"))
 	fmt.Printf("  %-18s          Show this help message\n\n", green("help"))
 
@@ -46,4 +53,5 @@ func PrintUsage() {
 	fmt.Printf("%s\n", yellow("GLOBAL FLAGS"))
 	fmt.Printf("  %s, %s      Show GCT version information\n", green("-v"), green("--version"))
 	fmt.Printf("  %s         Generate the response again not from the cache\n", green("--no-cache"))
+	fmt.Printf("  %s   Show the model's reasoning alongside the answer\n", green("--show-reasoning"))
 }
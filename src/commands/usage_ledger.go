@@ -0,0 +1,105 @@
+package commands
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"gct/src/ai"
+	"gct/src/config"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type usageEntry struct {
+	Time         time.Time `json:"time"`
+	Command      string    `json:"command"`
+	Provider     string    `json:"provider"`
+	Model        string    `json:"model"`
+	Repo         string    `json:"repo,omitempty"`
+	InputTokens  int       `json:"input_tokens"`
+	OutputTokens int       `json:"output_tokens"`
+
+	CacheReadTokens  int  `json:"cache_read_tokens,omitempty"`
+	CacheWriteTokens int  `json:"cache_write_tokens,omitempty"`
+	UsageUnknown     bool `json:"usage_unknown,omitempty"`
+}
+
+func getUsageLedgerPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "gct", "usage.jsonl"), nil
+}
+
+func recordUsage(command string, provider config.ProviderConfig, usage ai.Usage) {
+	path, err := getUsageLedgerPath()
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+
+	repo, _ := findGitRoot()
+	entry := usageEntry{
+		Time:         time.Now(),
+		Command:      command,
+		Provider:     provider.Provider,
+		Model:        provider.Model,
+		Repo:         repo,
+		InputTokens:  usage.InputTokens,
+		OutputTokens: usage.OutputTokens,
+
+		CacheReadTokens:  usage.CacheReadTokens,
+		CacheWriteTokens: usage.CacheWriteTokens,
+		UsageUnknown:     usage == ai.Usage{},
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	_, _ = file.Write(append(line, '\n'))
+}
+
+func readUsageLedger() ([]usageEntry, error) {
+	path, err := getUsageLedgerPath()
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open usage ledger: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	var entries []usageEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry usageEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read usage ledger: %w", err)
+	}
+	return entries, nil
+}
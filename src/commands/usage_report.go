@@ -0,0 +1,206 @@
+package commands
+
+import (
+	"fmt"
+	"gct/src/ai"
+	"gct/src/config"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+const defaultUsageDays = 30
+
+const (
+	defaultCacheReadPriceRatio  = 0.1
+	defaultCacheWritePriceRatio = 1.25
+)
+
+type usageTotals struct {
+	Calls           int
+	InputTokens     int
+	OutputTokens    int
+	CacheReadTokens int
+	Cost            float64
+	Priced          bool
+	Unpriced        bool
+	Unknown         int
+}
+
+func modelPrice(cfg *config.Config, entry usageEntry) (config.ModelPricing, bool) {
+	price := ai.LookupModel(cfg, config.ProviderConfig{Provider: entry.Provider, Model: entry.Model}).Pricing
+	return price, price.Input > 0 || price.Output > 0
+}
+
+func (t *usageTotals) add(entry usageEntry, cfg *config.Config) {
+	t.Calls++
+	if entry.UsageUnknown {
+		t.Unknown++
+		return
+	}
+	t.InputTokens += entry.InputTokens + entry.CacheReadTokens + entry.CacheWriteTokens
+	t.OutputTokens += entry.OutputTokens
+	t.CacheReadTokens += entry.CacheReadTokens
+
+	price, ok := modelPrice(cfg, entry)
+	if !ok {
+		t.Unpriced = true
+		return
+	}
+	cacheRead := price.CacheRead
+	if cacheRead == 0 {
+		cacheRead = price.Input * defaultCacheReadPriceRatio
+	}
+	cacheWrite := price.CacheWrite
+	if cacheWrite == 0 {
+		cacheWrite = price.Input * defaultCacheWritePriceRatio
+	}
+
+	t.Priced = true
+	t.Cost += (float64(entry.InputTokens)*price.Input +
+		float64(entry.CacheReadTokens)*cacheRead +
+		float64(entry.CacheWriteTokens)*cacheWrite +
+		float64(entry.OutputTokens)*price.Output) / 1_000_000
+}
+
+func UsageCommand(args []string) {
+	red := color.New(color.FgRed).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+	cyan := color.New(color.FgCyan).SprintFunc()
+	bold := color.New(color.Bold).SprintFunc()
+
+	days := defaultUsageDays
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--days":
+			if i+1 >= len(args) {
+				fmt.Println(yellow("Usage: gct usage [--days <n>]"))
+				return
+			}
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil || n <= 0 {
+				fmt.Printf("%s --days expects a positive number, got '%s'\n", red("Error:"), args[i+1])
+				return
+			}
+			days = n
+			i++
+		default:
+			fmt.Println(yellow("Usage: gct usage [--days <n>]"))
+			return
+		}
+	}
+
+	entries, err := readUsageLedger()
+	if err != nil {
+		fmt.Printf("%s %v\n", red("Error:"), err)
+		return
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		cfg = &config.Config{}
+	}
+
+	since := time.Now().AddDate(0, 0, -days)
+	var total usageTotals
+	byDay := map[string]*usageTotals{}
+	byModel := map[string]*usageTotals{}
+	byCommand := map[string]*usageTotals{}
+
+	for _, entry := range entries {
+		if entry.Time.Before(since) {
+			continue
+		}
+		total.add(entry, cfg)
+		addUsage(byDay, entry.Time.Local().Format("2006-01-02"), entry, cfg)
+		addUsage(byModel, fmt.Sprintf("%s (%s)", entry.Model, entry.Provider), entry, cfg)
+		addUsage(byCommand, "ai "+entry.Command, entry, cfg)
+	}
+
+	fmt.Printf("%s AI token usage over the last %d days\n", bold("GCT"), days)
+	if total.Calls == 0 {
+		fmt.Printf("\n%s No AI requests recorded yet.\n", cyan("ℹ"))
+		return
+	}
+
+	printUsageSection("BY DAY", byDay, true)
+	printUsageSection("BY MODEL", byModel, false)
+	printUsageSection("BY COMMAND", byCommand, false)
+
+	fmt.Printf("\n%s\n", yellow("TOTAL"))
+	printUsageRow("all requests", &total)
+
+	if !total.Priced {
+		fmt.Printf("\n%s No prices are known for these models. Add a 'pricing' table to gct.yaml to see estimated costs.\n", cyan("ℹ"))
+	} else if total.Unpriced {
+		fmt.Printf("\n%s Some models have no known price, so costs are incomplete. Add them to 'pricing' in gct.yaml.\n", cyan("ℹ"))
+	}
+	if total.Unknown > 0 {
+		fmt.Printf("\n%s %d of these requests went to a provider that did not report token usage, so their tokens are not counted.\n",
+			cyan("ℹ"), total.Unknown)
+	}
+}
+
+func addUsage(groups map[string]*usageTotals, key string, entry usageEntry, cfg *config.Config) {
+	totals, ok := groups[key]
+	if !ok {
+		totals = &usageTotals{}
+		groups[key] = totals
+	}
+	totals.add(entry, cfg)
+}
+
+func printUsageSection(title string, groups map[string]*usageTotals, newestFirst bool) {
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if newestFirst {
+			return keys[i] > keys[j]
+		}
+		if groups[keys[i]].Calls != groups[keys[j]].Calls {
+			return groups[keys[i]].Calls > groups[keys[j]].Calls
+		}
+		return keys[i] < keys[j]
+	})
+
+	fmt.Printf("\n%s\n", yellow(title))
+	for _, key := range keys {
+		printUsageRow(key, groups[key])
+	}
+}
+
+func printUsageRow(label string, totals *usageTotals) {
+	green := color.New(color.FgGreen).SprintFunc()
+	faint := color.New(color.Faint).SprintFunc()
+
+	cost := faint("-")
+	if totals.Priced {
+		cost = fmt.Sprintf("$%.4f", totals.Cost)
+		if totals.Unpriced {
+			cost += faint("+")
+		}
+	}
+
+	fmt.Printf("  %-36s %5d calls  %12s in  %12s cached  %10s out  %s\n",
+		green(label), totals.Calls, formatTokenCount(totals.InputTokens), formatTokenCount(totals.CacheReadTokens),
+		formatTokenCount(totals.OutputTokens), cost)
+}
+
+func formatTokenCount(n int) string {
+	digits := strconv.Itoa(n)
+	var sb strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			sb.WriteRune(',')
+		}
+		sb.WriteRune(digit)
+	}
+	return sb.String()
+}
@@ -4,6 +4,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/kelseyhightower/envconfig"
@@ -14,25 +18,200 @@ type CacheConfig struct {
 	Enabled bool `yaml:"enabled" envconfig:"GCT_CACHE_ENABLED"`
 }
 
+type RetryConfig struct {
+	MaxAttempts  int           `yaml:"max_attempts,omitempty" envconfig:"GCT_RETRY_MAX_ATTEMPTS"`
+	InitialDelay time.Duration `yaml:"initial_delay,omitempty" envconfig:"GCT_RETRY_INITIAL_DELAY"`
+	MaxDelay     time.Duration `yaml:"max_delay,omitempty" envconfig:"GCT_RETRY_MAX_DELAY"`
+}
+
+type DeadlineConfig struct {
+	Default time.Duration `yaml:"default,omitempty" envconfig:"GCT_DEADLINE"`
+	Commit  time.Duration `yaml:"commit,omitempty" ignored:"true"`
+	Diff    time.Duration `yaml:"diff,omitempty" ignored:"true"`
+	Log     time.Duration `yaml:"log,omitempty" ignored:"true"`
+	PR      time.Duration `yaml:"pr,omitempty" ignored:"true"`
+	Issue   time.Duration `yaml:"issue,omitempty" ignored:"true"`
+}
+
+type HTTPConfig struct {
+	Timeout        time.Duration     `yaml:"timeout,omitempty" envconfig:"GCT_HTTP_TIMEOUT"`
+	ConnectTimeout time.Duration     `yaml:"connect_timeout,omitempty" envconfig:"GCT_HTTP_CONNECT_TIMEOUT"`
+	Proxy          string            `yaml:"proxy,omitempty" envconfig:"GCT_HTTP_PROXY"`
+	CAFile         string            `yaml:"ca_file,omitempty" envconfig:"GCT_HTTP_CA_FILE"`
+	ClientCert     string            `yaml:"client_cert,omitempty" envconfig:"GCT_HTTP_CLIENT_CERT"`
+	ClientKey      string            `yaml:"client_key,omitempty" envconfig:"GCT_HTTP_CLIENT_KEY"`
+	Headers        map[string]string `yaml:"headers,omitempty" envconfig:"GCT_HTTP_HEADERS"`
+	Record         string            `yaml:"record,omitempty" envconfig:"GCT_HTTP_RECORD"`
+	Replay         string            `yaml:"replay,omitempty" envconfig:"GCT_HTTP_REPLAY"`
+}
+
+type GenerationParams struct {
+	MaxTokens   int      `yaml:"max_tokens,omitempty" envconfig:"GCT_MAX_TOKENS"`
+	Temperature *float64 `yaml:"temperature,omitempty" envconfig:"GCT_TEMPERATURE"`
+	TopP        *float64 `yaml:"top_p,omitempty" envconfig:"GCT_TOP_P"`
+	Stop        []string `yaml:"stop,omitempty" envconfig:"GCT_STOP"`
+
+	ReasoningEffort string `yaml:"reasoning_effort,omitempty" envconfig:"GCT_REASONING_EFFORT"`
+	ThinkingBudget  int    `yaml:"thinking_budget,omitempty" envconfig:"GCT_THINKING_BUDGET"`
+}
+
+type GenerationConfig struct {
+	GenerationParams `yaml:",inline"`
+	Commit           GenerationParams `yaml:"commit,omitempty" ignored:"true"`
+	Diff             GenerationParams `yaml:"diff,omitempty" ignored:"true"`
+	Log              GenerationParams `yaml:"log,omitempty" ignored:"true"`
+	PR               GenerationParams `yaml:"pr,omitempty" ignored:"true"`
+	Issue            GenerationParams `yaml:"issue,omitempty" ignored:"true"`
+}
+
+type ModelPricing struct {
+	Input      float64 `yaml:"input"`
+	Output     float64 `yaml:"output"`
+	CacheRead  float64 `yaml:"cache_read,omitempty"`
+	CacheWrite float64 `yaml:"cache_write,omitempty"`
+}
+
+type ModelOverride struct {
+	ContextWindow   int `yaml:"context_window,omitempty"`
+	MaxOutputTokens int `yaml:"max_output_tokens,omitempty"`
+}
+
 type GuidesConfig struct {
 	Paths []string `yaml:"guides"`
 }
 
+type ProviderConfig struct {
+	Provider           string `yaml:"provider" envconfig:"GCT_PROVIDER"`
+	Model              string `yaml:"model" envconfig:"GCT_MODEL"`
+	APIKey             string `yaml:"api" envconfig:"GCT_API_KEY"`
+	APIKeyCommand      string `yaml:"api_key_command,omitempty" envconfig:"GCT_API_KEY_COMMAND"`
+	Endpoint           string `yaml:"endpoint,omitempty" envconfig:"GCT_ENDPOINT"`
+	ContextWindow      int    `yaml:"context_window,omitempty" envconfig:"GCT_CONTEXT_WINDOW"`
+	GCPProjectID       string `yaml:"gcp_project_id,omitempty" envconfig:"GCT_GCP_PROJECT_ID"`
+	GCPRegion          string `yaml:"gcp_region,omitempty" envconfig:"GCT_GCP_REGION"`
+	GCPCredentialsFile string `yaml:"gcp_credentials_file,omitempty" envconfig:"GCT_GCP_CREDENTIALS_FILE"`
+	GCPTokenURL        string `yaml:"gcp_token_url,omitempty" envconfig:"GCT_GCP_TOKEN_URL"`
+	AWSAccessKeyID     string `yaml:"aws_access_key_id,omitempty" envconfig:"GCT_AWS_ACCESS_KEY_ID"`
+	AWSSecretAccessKey string `yaml:"aws_secret_access_key,omitempty" envconfig:"GCT_AWS_SECRET_ACCESS_KEY"`
+	AWSRegion          string `yaml:"aws_region,omitempty" envconfig:"GCT_AWS_REGION"`
+	AWSProfile         string `yaml:"aws_profile,omitempty" envconfig:"GCT_AWS_PROFILE"`
+	AWSRoleARN         string `yaml:"aws_role_arn,omitempty" envconfig:"GCT_AWS_ROLE_ARN"`
+	AzureResourceName  string `yaml:"azure_resource_name,omitempty" envconfig:"GCT_AZURE_RESOURCE_NAME"`
+	OllamaKeepAlive    string `yaml:"ollama_keep_alive,omitempty" envconfig:"GCT_OLLAMA_KEEP_ALIVE"`
+	OllamaContextSize  int    `yaml:"ollama_context_size,omitempty" envconfig:"GCT_OLLAMA_CONTEXT_SIZE"`
+	FakeScript         string `yaml:"fake_script,omitempty" envconfig:"GCT_FAKE_SCRIPT"`
+
+	SafetySettings map[string]string `yaml:"safety_settings,omitempty" envconfig:"GCT_SAFETY_SETTINGS"`
+
+	commandFromProject bool
+	fallback           bool
+}
+
+type CustomProviderConfig struct {
+	Name     string                 `yaml:"name"`
+	BaseURL  string                 `yaml:"base_url"`
+	Dialect  string                 `yaml:"dialect,omitempty"`
+	Auth     string                 `yaml:"auth,omitempty"`
+	AuthName string                 `yaml:"auth_name,omitempty"`
+	Headers  map[string]string      `yaml:"headers,omitempty"`
+	Body     map[string]interface{} `yaml:"body,omitempty"`
+}
+
 type Config struct {
-	Name               string       `yaml:"name" envconfig:"GCT_NAME"`
-	Provider           string       `yaml:"provider" envconfig:"GCT_PROVIDER"`
-	Model              string       `yaml:"model" envconfig:"GCT_MODEL"`
-	APIKey             string       `yaml:"api" envconfig:"GCT_API_KEY"`
-	Endpoint           string       `yaml:"endpoint,omitempty" envconfig:"GCT_ENDPOINT"`
-	Commits            GuidesConfig `yaml:"commits"`
-	Changelogs         GuidesConfig `yaml:"changelogs"`
-	GCPProjectID       string       `yaml:"gcp_project_id,omitempty" envconfig:"GCT_GCP_PROJECT_ID"`
-	GCPRegion          string       `yaml:"gcp_region,omitempty" envconfig:"GCT_GCP_REGION"`
-	AWSAccessKeyID     string       `yaml:"aws_access_key_id,omitempty" envconfig:"GCT_AWS_ACCESS_KEY_ID"`
-	AWSSecretAccessKey string       `yaml:"aws_secret_access_key,omitempty" envconfig:"GCT_AWS_SECRET_ACCESS_KEY"`
-	AWSRegion          string       `yaml:"aws_region,omitempty" envconfig:"GCT_AWS_REGION"`
-	AzureResourceName  string       `yaml:"azure_resource_name,omitempty" envconfig:"GCT_AZURE_RESOURCE_NAME"`
-	Cache              CacheConfig  `yaml:"cache,omitempty"`
+	Name              string `yaml:"name" envconfig:"GCT_NAME"`
+	ProviderConfig    `yaml:",inline"`
+	Commits           GuidesConfig             `yaml:"commits"`
+	Changelogs        GuidesConfig             `yaml:"changelogs"`
+	Fallbacks         []ProviderConfig         `yaml:"fallbacks,omitempty" ignored:"true"`
+	FallbackOnBlocked bool                     `yaml:"fallback_on_blocked,omitempty" ignored:"true"`
+	Providers         []CustomProviderConfig   `yaml:"providers,omitempty" ignored:"true"`
+	Cache             CacheConfig              `yaml:"cache,omitempty"`
+	Retry             RetryConfig              `yaml:"retry,omitempty"`
+	HTTP              HTTPConfig               `yaml:"http,omitempty"`
+	Deadline          DeadlineConfig           `yaml:"deadline,omitempty"`
+	Generation        GenerationConfig         `yaml:"generation,omitempty"`
+	Models            map[string]ModelOverride `yaml:"models,omitempty" ignored:"true"`
+	Pricing           map[string]ModelPricing  `yaml:"pricing,omitempty" ignored:"true"`
+}
+
+func (c *Config) ProviderChain() []ProviderConfig {
+	chain := []ProviderConfig{c.ProviderConfig}
+	for _, fallback := range c.Fallbacks {
+		if fallback.Provider == "" {
+			continue
+		}
+		fallback.fallback = true
+		chain = append(chain, fallback)
+	}
+	return chain
+}
+
+func (p ProviderConfig) IsFallback() bool {
+	return p.fallback
+}
+
+func (c *Config) WithProvider(provider ProviderConfig) *Config {
+	derived := *c
+	derived.ProviderConfig = provider
+	return &derived
+}
+
+func (g GenerationConfig) ForCommand(command string) GenerationParams {
+	var override GenerationParams
+	switch command {
+	case "commit":
+		override = g.Commit
+	case "diff":
+		override = g.Diff
+	case "log":
+		override = g.Log
+	case "pr":
+		override = g.PR
+	case "issue":
+		override = g.Issue
+	}
+
+	params := g.GenerationParams
+	if override.MaxTokens > 0 {
+		params.MaxTokens = override.MaxTokens
+	}
+	if override.Temperature != nil {
+		params.Temperature = override.Temperature
+	}
+	if override.TopP != nil {
+		params.TopP = override.TopP
+	}
+	if len(override.Stop) > 0 {
+		params.Stop = override.Stop
+	}
+	if override.ReasoningEffort != "" {
+		params.ReasoningEffort = override.ReasoningEffort
+	}
+	if override.ThinkingBudget > 0 {
+		params.ThinkingBudget = override.ThinkingBudget
+	}
+	return params
+}
+
+func (d DeadlineConfig) ForCommand(command string) time.Duration {
+	var override time.Duration
+	switch command {
+	case "commit":
+		override = d.Commit
+	case "diff":
+		override = d.Diff
+	case "log":
+		override = d.Log
+	case "pr":
+		override = d.PR
+	case "issue":
+		override = d.Issue
+	}
+
+	if override > 0 {
+		return override
+	}
+	return d.Default
 }
 
 func loadConfigFromFile(path string) (*Config, error) {
@@ -92,25 +271,26 @@ func findGlobalConfig() (string, bool) {
 	return "", false
 }
 
+func FindConfigFile() (string, bool) {
+	if localPath, found := findLocalConfig(); found {
+		return localPath, true
+	}
+	return findGlobalConfig()
+}
+
 func LoadConfig() (*Config, error) {
 	_ = godotenv.Load()
 
 	var cfg *Config
 	var err error
 
-	if localPath, found := findLocalConfig(); found {
-		cfg, err = loadConfigFromFile(localPath)
+	if path, found := FindConfigFile(); found {
+		cfg, err = loadConfigFromFile(path)
 		if err != nil {
 			return nil, err
 		}
-	}
-
-	if cfg == nil {
-		if globalPath, found := findGlobalConfig(); found {
-			cfg, err = loadConfigFromFile(globalPath)
-			if err != nil {
-				return nil, err
-			}
+		if globalPath, _ := findGlobalConfig(); path != globalPath {
+			cfg.markProjectCommands()
 		}
 	}
 
@@ -122,6 +302,9 @@ func LoadConfig() (*Config, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to process environment variables: %w", err)
 	}
+	if _, ok := os.LookupEnv("GCT_API_KEY_COMMAND"); ok {
+		cfg.commandFromProject = false
+	}
 
 	if cfg.Provider == "" {
 		return nil, fmt.Errorf("no AI provider configured. Please run 'gct init' or configure environment variables")
@@ -129,3 +312,54 @@ func LoadConfig() (*Config, error) {
 
 	return cfg, nil
 }
+
+type EnvOverride struct {
+	Variable string
+	Field    string
+}
+
+func EnvOverrides() (overrides []EnvOverride, unknown []string) {
+	_ = godotenv.Load()
+
+	known := map[string]string{}
+	collectEnvFields(reflect.TypeOf(Config{}), "", known)
+
+	for _, entry := range os.Environ() {
+		name, _, _ := strings.Cut(entry, "=")
+		if !strings.HasPrefix(name, "GCT_") {
+			continue
+		}
+		if field, ok := known[name]; ok {
+			overrides = append(overrides, EnvOverride{Variable: name, Field: field})
+		} else {
+			unknown = append(unknown, name)
+		}
+	}
+
+	sort.Slice(overrides, func(i, j int) bool { return overrides[i].Variable < overrides[j].Variable })
+	sort.Strings(unknown)
+	return overrides, unknown
+}
+
+func collectEnvFields(t reflect.Type, prefix string, known map[string]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Tag.Get("ignored") == "true" {
+			continue
+		}
+
+		name, options, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		path := prefix
+		if options != "inline" && !field.Anonymous {
+			path = strings.TrimPrefix(prefix+"."+name, ".")
+		}
+
+		if variable := field.Tag.Get("envconfig"); variable != "" {
+			known[variable] = path
+			continue
+		}
+		if field.Type.Kind() == reflect.Struct {
+			collectEnvFields(field.Type, path, known)
+		}
+	}
+}
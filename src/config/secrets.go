@@ -0,0 +1,153 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+const secretFilePrefix = "file:"
+
+var ErrProjectSecretCommand = errors.New("api_key_command is not read from a project gct.yaml")
+
+var secretEnvReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+var (
+	secretCommandsMu sync.Mutex
+	secretCommands   = map[string]string{}
+)
+
+func IsSecretReference(value string) bool {
+	return strings.HasPrefix(value, secretFilePrefix) || secretEnvReference.MatchString(value)
+}
+
+func (p ProviderConfig) HasInlineSecrets() bool {
+	for _, value := range []string{p.APIKey, p.AWSAccessKeyID, p.AWSSecretAccessKey} {
+		if value != "" && !IsSecretReference(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Config) markProjectCommands() {
+	c.commandFromProject = c.APIKeyCommand != ""
+	for i := range c.Fallbacks {
+		c.Fallbacks[i].commandFromProject = c.Fallbacks[i].APIKeyCommand != ""
+	}
+}
+
+func (p ProviderConfig) ResolveSecrets() (ProviderConfig, error) {
+	resolved := p
+	var err error
+
+	if p.commandFromProject && p.APIKey == "" {
+		return p, fmt.Errorf("%w: set it in your user config (%s) or GCT_API_KEY_COMMAND instead", ErrProjectSecretCommand, userConfigHint())
+	}
+
+	if resolved.APIKey == "" && resolved.APIKeyCommand != "" {
+		if resolved.APIKey, err = runSecretCommand(resolved.APIKeyCommand); err != nil {
+			return p, fmt.Errorf("api_key_command failed: %w", err)
+		}
+	} else if resolved.APIKey, err = ResolveSecret(p.APIKey); err != nil {
+		return p, fmt.Errorf("could not resolve 'api': %w", err)
+	}
+
+	if resolved.AWSAccessKeyID, err = ResolveSecret(p.AWSAccessKeyID); err != nil {
+		return p, fmt.Errorf("could not resolve 'aws_access_key_id': %w", err)
+	}
+	if resolved.AWSSecretAccessKey, err = ResolveSecret(p.AWSSecretAccessKey); err != nil {
+		return p, fmt.Errorf("could not resolve 'aws_secret_access_key': %w", err)
+	}
+	return resolved, nil
+}
+
+func ResolveSecret(value string) (string, error) {
+	var missing []string
+	expanded := secretEnvReference.ReplaceAllStringFunc(value, func(reference string) string {
+		name := secretEnvReference.FindStringSubmatch(reference)[1]
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return v
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variable %s is not set", strings.Join(missing, ", "))
+	}
+
+	path, ok := strings.CutPrefix(expanded, secretFilePrefix)
+	if !ok {
+		return expanded, nil
+	}
+	return readSecretFile(path)
+}
+
+func readSecretFile(path string) (string, error) {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to find home directory: %w", err)
+		}
+		path = filepath.Join(home, rest)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+	secret := strings.TrimSpace(string(data))
+	if secret == "" {
+		return "", fmt.Errorf("secret file %s is empty", path)
+	}
+	return secret, nil
+}
+
+func runSecretCommand(command string) (string, error) {
+	secretCommandsMu.Lock()
+	defer secretCommandsMu.Unlock()
+
+	if secret, ok := secretCommands[command]; ok {
+		return secret, nil
+	}
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("pwsh", "-Command", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if detail := strings.TrimSpace(stderr.String()); detail != "" {
+			return "", fmt.Errorf("'%s': %w: %s", command, err, detail)
+		}
+		return "", fmt.Errorf("'%s': %w", command, err)
+	}
+
+	secret := strings.TrimSuffix(strings.TrimSuffix(stdout.String(), "\n"), "\r")
+	if secret == "" {
+		return "", fmt.Errorf("'%s' printed nothing", command)
+	}
+	if strings.ContainsAny(secret, "\r\n") {
+		return "", fmt.Errorf("'%s' printed more than one line; it should print only the key", command)
+	}
+	secretCommands[command] = secret
+	return secret, nil
+}
+
+func userConfigHint() string {
+	if configDir, err := os.UserConfigDir(); err == nil {
+		return filepath.Join(configDir, "gct", "config.yaml")
+	}
+	return "~/.config/gct/config.yaml"
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func setupConfigTest(t *testing.T) (project, home string) {
+	t.Helper()
+	home = t.TempDir()
+	project = t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	for _, entry := range os.Environ() {
+		if name, _, _ := strings.Cut(entry, "="); strings.HasPrefix(name, "GCT_") {
+			t.Setenv(name, "")
+			os.Unsetenv(name)
+		}
+	}
+	t.Chdir(project)
+	return project, home
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func resolveTestConfig(t *testing.T) (ProviderConfig, error) {
+	t.Helper()
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	return cfg.ResolveSecrets()
+}
+
+func TestProjectConfigDoesNotRunSecretCommand(t *testing.T) {
+	project, _ := setupConfigTest(t)
+	marker := filepath.Join(project, "ran")
+	writeTestFile(t, filepath.Join(project, "gct.yaml"),
+		"provider: OpenAI\nmodel: gpt-4o\napi_key_command: touch "+marker+" && echo sk-project\n")
+
+	if _, err := resolveTestConfig(t); !errors.Is(err, ErrProjectSecretCommand) {
+		t.Fatalf("expected ErrProjectSecretCommand, got %v", err)
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Fatal("api_key_command ran from a project gct.yaml")
+	}
+
+	t.Setenv("GCT_API_KEY_COMMAND", "echo sk-user")
+	resolved, err := resolveTestConfig(t)
+	if err != nil || resolved.APIKey != "sk-user" {
+		t.Fatalf("GCT_API_KEY_COMMAND should be used: %v, %q", err, resolved.APIKey)
+	}
+}
+
+func TestUserConfigRunsSecretCommand(t *testing.T) {
+	_, home := setupConfigTest(t)
+	writeTestFile(t, filepath.Join(home, ".config", "gct", "config.yaml"),
+		"provider: OpenAI\nmodel: gpt-4o\napi_key_command: printf 'sk-user\\n'\n")
+
+	resolved, err := resolveTestConfig(t)
+	if err != nil || resolved.APIKey != "sk-user" {
+		t.Fatalf("api_key_command from the user config: %v, %q", err, resolved.APIKey)
+	}
+}
+
+func TestDotEnvSecretCommand(t *testing.T) {
+	project, _ := setupConfigTest(t)
+	writeTestFile(t, filepath.Join(project, "gct.yaml"), "provider: OpenAI\nmodel: gpt-4o\n")
+	writeTestFile(t, filepath.Join(project, ".env"), "GCT_API_KEY_COMMAND='echo sk-dotenv'\n")
+	t.Cleanup(func() { os.Unsetenv("GCT_API_KEY_COMMAND") })
+
+	resolved, err := resolveTestConfig(t)
+	if err != nil || resolved.APIKey != "sk-dotenv" {
+		t.Fatalf("GCT_API_KEY_COMMAND from .env: %v, %q", err, resolved.APIKey)
+	}
+}
+
+func TestRunSecretCommandOutput(t *testing.T) {
+	tests := []struct {
+		command string
+		want    string
+		fails   bool
+	}{
+		{"printf 'sk-one\\n'", "sk-one", false},
+		{"printf ' sk-spaced '", " sk-spaced ", false},
+		{"printf 'sk-one\\nsk-two\\n'", "", true},
+		{"true", "", true},
+	}
+	for _, tt := range tests {
+		got, err := runSecretCommand(tt.command)
+		if (err != nil) != tt.fails || got != tt.want {
+			t.Errorf("runSecretCommand(%q) = %q, %v", tt.command, got, err)
+		}
+	}
+}
@@ -17,6 +17,8 @@ var (
 )
 
 func main() {
+	os.Args = parseGlobalFlags(os.Args)
+
 	if len(os.Args) < 2 || os.Args[1] == "help" {
 		commands.PrintUsage()
 		return
@@ -73,14 +75,6 @@ func main() {
 
 	command := os.Args[1]
 	args := os.Args[2:]
-	args2 := os.Args
-	for _, arg := range args2 {
-		if arg == "--no-cache" {
-			commands.NoCache = true
-
-			break
-		}
-	}
 
 	switch command {
 	case "init":
@@ -97,6 +91,16 @@ func main() {
 			return
 		}
 		commands.VersionCommand(VerBranch, VerStatus, VerNumber, VerCommit)
+	case "usage":
+		commands.UsageCommand(args)
+	case "models":
+		commands.ModelsCommand(args)
+	case "doctor":
+		if len(args) > 0 {
+			fmt.Println(color.YellowString("Usage: gct doctor (no arguments expected)"))
+			return
+		}
+		commands.DoctorCommand()
 	case "about":
 		if len(args) > 0 {
 			fmt.Println(color.YellowString("Usage: gct about (no arguments expected)"))
@@ -117,3 +121,18 @@ func main() {
 		commands.NotFoundCommand()
 	}
 }
+
+func parseGlobalFlags(args []string) []string {
+	remaining := make([]string, 0, len(args))
+	for _, arg := range args {
+		switch arg {
+		case "--no-cache":
+			commands.NoCache = true
+		case "--show-reasoning":
+			commands.ShowReasoning = true
+		default:
+			remaining = append(remaining, arg)
+		}
+	}
+	return remaining
+}